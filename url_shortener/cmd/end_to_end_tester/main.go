@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"regexp"
 	"strings"
 	"syscall"
 	"time"
@@ -28,6 +27,7 @@ const (
 
 	sourcePersistenceFile = "url_shortener/cmd/end_to_end_tester/persistence.json"
 	persistenceFile       = "build/url_shortener/persistence.json"
+	walFile               = persistenceFile + ".wal"
 
 	serverExecutable = "build/url_shortener/http_server"
 
@@ -41,8 +41,6 @@ const (
 	redirectURL = "http://localhost:9090/87aefef"
 
 	statisticsURL = "http://localhost:9090/statistics?format=json"
-
-	shortURLRegExp = `">(.*) ->`
 )
 
 // https://stackoverflow.com/a/21061062
@@ -133,18 +131,36 @@ func testStatisticsJSON() error {
 		return fmt.Errorf("testStatisticsJSON: unable to decode, error: %v", err)
 	}
 
+	if stats.Version != shorten.StatsVersion {
+		return fmt.Errorf("testStatisticsJSON: expected statistics JSON version %v, but got: %v", shorten.StatsVersion, stats.Version)
+	}
+
 	gotTotalURL := stats.ServerStats.TotalURL
 	if gotTotalURL != 1 {
 		return fmt.Errorf("testStatisticsJSON: expected TotalURL on statistics JSON to be 1, but got: %v", gotTotalURL)
 	}
 
+	// only the redirects of testNonExistentHash and testWeatherHash count
+	gotRedirects := stats.ServerStats.Redirects
+	if gotRedirects.Success != 1 || gotRedirects.Failed != 1 {
+		return fmt.Errorf("testStatisticsJSON: expected 1 succeeded and 1 failed redirect, but got: %+v", gotRedirects)
+	}
+
 	return nil
 }
 
 func testShortenURLAddingANonExistentURL() error {
 	client := &http.Client{}
 
-	response, err := client.Get(shortenWeatherURLRome)
+	request, err := http.NewRequest(http.MethodGet, shortenWeatherURLRome, nil)
+
+	if err != nil {
+		return fmt.Errorf("testShortenURLAddingANonExistentURL: got error creating request: %v", err)
+	}
+
+	request.Header.Set("Accept", "text/plain")
+
+	response, err := client.Do(request)
 
 	if err != nil {
 		return fmt.Errorf("testShortenURLAddingANonExistentURL: got error on get: %v", err)
@@ -162,11 +178,9 @@ func testShortenURLAddingANonExistentURL() error {
 		return fmt.Errorf("testShortenURLAddingANonExistentURL: error reading body: %v", err)
 	}
 
-	body := string(bodyByte)
-	re := regexp.MustCompile(shortURLRegExp)
-	gotShortURL := strings.TrimSpace(re.FindStringSubmatch(body)[1])
-	if gotShortURL == "" {
-		return fmt.Errorf("testShortenURLAddingANonExistentURL: no short URL on shorten page")
+	gotShortURL := strings.TrimSpace(string(bodyByte))
+	if gotShortURL != redirectURL {
+		return fmt.Errorf("testShortenURLAddingANonExistentURL: incorrect short URL, got: %s, want: %s", gotShortURL, redirectURL)
 	}
 
 	return nil
@@ -269,6 +283,11 @@ func main() {
 		os.Exit(exitCodeError)
 	}
 
+	if err := os.Remove(walFile); err != nil && !os.IsNotExist(err) {
+		log.Println("main: stale write-ahead log not removed. Error:", err)
+		os.Exit(exitCodeError)
+	}
+
 	exitCode := trueMain()
 
 	if err := testURLAddedToPersistenceFile(); err != nil {
@@ -3,13 +3,17 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/rgianassi/learning/go/url_shortener/shorten"
 )
@@ -17,55 +21,207 @@ import (
 var (
 	address     = flag.String("addr", "localhost:9090", "server listen address")
 	persistence = flag.String("load", "persistence.json", "persistence JSON file for URLs")
+	walFile     = flag.String("wal", "", "write-ahead log file for URLs, the -load file with a .wal suffix when not given, empty to disable")
+
+	tlsCert           = flag.String("tls-cert", "", "TLS certificate file, serves HTTPS together with -tls-key")
+	tlsKey            = flag.String("tls-key", "", "TLS private key file, serves HTTPS together with -tls-cert")
+	tlsDev            = flag.Bool("tls-dev", false, "serve HTTPS with a self-signed certificate generated at startup, for development")
+	httpsRedirectAddr = flag.String("https-redirect-addr", "", "listen address of a plain HTTP server redirecting to HTTPS, empty to disable")
+
+	generator         = flag.String("generator", "hash", "short URL generator: hash, counter or random")
+	codeLength        = flag.Int("code-length", 7, "length of the short URLs made by the random generator")
+	alphabet          = flag.String("alphabet", string(shorten.Base62Alphabet), "characters of the short URLs made by the counter and random generators")
+	excludeLookAlikes = flag.Bool("exclude-lookalikes", false, "exclude look-alike characters from the short URLs alphabet")
+	counterFile       = flag.String("counter-file", "counter.json", "persistence JSON file for the counter generator state")
+
+	schemes   = flag.String("schemes", "http,https", "comma separated schemes allowed in long URLs")
+	selfHosts = flag.String("self-hosts", "", "comma separated further hosts the server is reachable at, links to them are refused")
+	sortQuery = flag.Bool("sort-query", false, "sort query parameters when normalizing long URLs")
+
+	redirectCode = flag.Int("redirect-code", http.StatusSeeOther, "status code of the redirects: 301, 302, 303, 307 or 308")
+	passQuery    = flag.Bool("pass-query", false, "append the query string of short URL requests to the long URLs")
+
+	publicURL      = flag.String("public-url", "", "base URL the server is publicly reachable at, as in https://sho.rt, used to build short links")
+	trustForwarded = flag.Bool("trust-forwarded", false, "build short links on X-Forwarded-Host and X-Forwarded-Proto, from -trusted-proxies when set")
+
+	routePrefix = flag.String("route-prefix", "", "path prefix all routes are mounted under, as in /s/")
+
+	createRate     = flag.Float64("create-rate", 0, "short URLs a client may create per second, 0 to disable the limit")
+	createBurst    = flag.Int("create-burst", 10, "short URLs a client may create in a burst")
+	redirectRate   = flag.Float64("redirect-rate", 0, "redirects a client may request per second, 0 to disable the limit")
+	redirectBurst  = flag.Int("redirect-burst", 100, "redirects a client may request in a burst")
+	trustedProxies = flag.String("trusted-proxies", "", "comma separated IPs or CIDRs of proxies whose X-Forwarded-For is trusted")
+
+	apiKeysFile = flag.String("api-keys", "", "file of API keys required by every route but the redirects, one name and key per line, empty to disable")
+
+	adminAddr      = flag.String("admin-addr", "", "listen address of the admin API used by shortenctl: unix:PATH for a Unix socket or a loopback host:port, empty to disable")
+	adminTokenFile = flag.String("admin-token-file", "", "file of the token required by the admin API, mandatory with a host:port admin address")
+
+	accessLog    = flag.Bool("access-log", true, "log every request served")
+	recoverPanic = flag.Bool("recover", true, "turn panics of handlers into 500 responses")
+	requestID    = flag.Bool("request-id", true, "assign every request an X-Request-ID, logged in the access log")
+
+	snapshotInterval = flag.Duration("snapshot-interval", 0, "interval between background snapshots to the persistence file, 0 to disable")
+	reapInterval     = flag.Duration("reap-interval", time.Minute, "interval between removals of expired links, 0 to disable")
 )
 
+// unpersist loads the persistence file, a missing one is an empty cache.
+// Any other failure is fatal: the startup snapshot would replace the file
+// with the write-ahead log records only, losing the links it holds.
 func unpersist(cache *shorten.URLShortener) {
 	log.Println("loading persistence data from:", *persistence)
 
 	f, err := os.Open(*persistence)
+	if os.IsNotExist(err) {
+		log.Println("no persistence data, starting empty:", err)
+		return
+	}
 	if err != nil {
-		log.Println("error unpersisting:", err)
-	} else {
-		defer f.Close()
+		log.Fatalln("error opening persistence data:", err)
+	}
+	defer f.Close()
+
+	if err := cache.UnpersistFrom(bufio.NewReader(f)); err != nil {
+		log.Fatalln("error unpersisting:", err)
 	}
+}
+
+// resolveWALFile places the write-ahead log next to the persistence file
+// unless -wal is given
+func resolveWALFile() {
+	given := false
 
-	reader := bufio.NewReader(f)
+	flag.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "wal"
+	})
 
-	cache.UnpersistFrom(reader)
+	if !given {
+		*walFile = *persistence + ".wal"
+	}
 }
 
-func persist(cache *shorten.URLShortener) {
-	log.Println("storing persistence data to:", *persistence)
+func replay(cache *shorten.URLShortener) {
+	log.Println("replaying write-ahead log from:", *walFile)
 
-	f, err := os.Create(*persistence)
+	f, err := os.Open(*walFile)
+	if os.IsNotExist(err) {
+		return
+	}
 	if err != nil {
-		log.Fatalln("error persisting:", err)
+		log.Fatalln("error opening write-ahead log:", err)
 	}
 	defer f.Close()
 
-	writer := bufio.NewWriter(f)
+	applied, err := cache.ReplayWAL(bufio.NewReader(f))
+	if err != nil {
+		log.Fatalln("error replaying write-ahead log:", err)
+	}
+
+	log.Println("write-ahead log records replayed:", applied)
+}
+
+func persist(cache *shorten.URLShortener, counter *shorten.CounterGenerator) error {
+	log.Println("storing persistence data to:", *persistence)
+
+	if err := cache.SnapshotTo(*persistence); err != nil {
+		log.Println("error persisting:", err)
+		return err
+	}
+
+	if counter == nil {
+		return nil
+	}
+
+	if err := counter.SnapshotTo(*counterFile); err != nil {
+		log.Println("error persisting counter:", err)
+		return err
+	}
+
+	return nil
+}
 
-	if err := cache.PersistTo(writer); err != nil {
-		log.Println("error persisting writer:", err)
+// newCodeGenerator builds the generator selected by flags, the counter is
+// returned too when selected as its state has to be persisted
+func newCodeGenerator() (shorten.CodeGenerator, *shorten.CounterGenerator) {
+	config := shorten.GeneratorConfig{
+		Name:              *generator,
+		Alphabet:          *alphabet,
+		ExcludeLookAlikes: *excludeLookAlikes,
+		CodeLength:        *codeLength,
+		CounterFile:       *counterFile,
 	}
 
-	writer.Flush()
-	f.Sync()
+	codeGenerator, counter, err := shorten.NewCodeGenerator(config)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return codeGenerator, counter
+}
+
+func snapshotPeriodically(cache *shorten.URLShortener, counter *shorten.CounterGenerator, stop chan struct{}) {
+	ticker := time.NewTicker(*snapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			persist(cache, counter)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func reapPeriodically(cache *shorten.URLShortener, stop chan struct{}) {
+	ticker := time.NewTicker(*reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := cache.RemoveExpired()
+			if err != nil {
+				log.Println("error removing expired links:", err)
+			}
+			if removed > 0 {
+				log.Println("expired links removed:", removed)
+			}
+		case <-stop:
+			return
+		}
+	}
 }
 
-func setupHTTPServerShutdown(cache *shorten.URLShortener, server *http.Server, idleConnectionsClosed chan struct{}) {
+func openWAL() *shorten.WAL {
+	if *walFile == "" {
+		return nil
+	}
+
+	wal, err := shorten.OpenWAL(*walFile)
+	if err != nil {
+		log.Fatalln("error opening write-ahead log:", err)
+	}
+
+	return wal
+}
+
+func setupHTTPServerShutdown(cache *shorten.URLShortener, counter *shorten.CounterGenerator, servers []*http.Server, stopBackground, idleConnectionsClosed chan struct{}) {
 	signalChannel := make(chan os.Signal, 1)
 
-	signal.Notify(signalChannel, syscall.SIGINT, syscall.SIGKILL)
+	signal.Notify(signalChannel, syscall.SIGINT, syscall.SIGTERM)
 
 	<-signalChannel
 
 	log.Println("shutting down...")
 
-	persist(cache)
+	close(stopBackground)
+	persist(cache, counter)
 
-	if err := server.Shutdown(context.Background()); err != nil {
-		log.Printf("HTTP server Shutdown error: %v", err)
+	for _, server := range servers {
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Printf("HTTP server Shutdown error: %v", err)
+		}
 	}
 
 	close(idleConnectionsClosed)
@@ -77,22 +233,313 @@ func launchHTTPServer(server *http.Server) {
 	}
 }
 
+// launchHTTPSServer serves HTTPS with the certificate files or, when none
+// are given, with the certificates already in the server TLS configuration
+func launchHTTPSServer(server *http.Server) {
+	if err := server.ListenAndServeTLS(*tlsCert, *tlsKey); err != http.ErrServerClosed {
+		log.Fatalf("HTTPS server ListenAndServeTLS error: %v", err)
+	}
+}
+
+// configureTLS prepares the server for HTTPS as requested by the flags, it
+// tells if HTTPS is enabled
+func configureTLS(server *http.Server) bool {
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatalln("-tls-cert and -tls-key must be given together")
+	}
+
+	if *tlsDev && *tlsCert != "" {
+		log.Fatalln("-tls-dev and -tls-cert are mutually exclusive")
+	}
+
+	if !*tlsDev {
+		return *tlsCert != ""
+	}
+
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(*address); err == nil && host != "" && !containsHost(hosts, host) {
+		hosts = append(hosts, host)
+	}
+
+	certificate, err := shorten.SelfSignedCertificate(hosts, 365*24*time.Hour)
+	if err != nil {
+		log.Fatalln("error generating self-signed certificate:", err)
+	}
+
+	server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	log.Println("serving HTTPS with a self-signed certificate for", strings.Join(hosts, ", "))
+
+	return true
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, candidate := range hosts {
+		if strings.EqualFold(candidate, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newAdminServer returns the server of the admin API listening on the admin
+// address, its snapshots persist the cache and the counter
+func newAdminServer(cache *shorten.URLShortener, counter *shorten.CounterGenerator) (*http.Server, net.Listener) {
+	listener, err := shorten.ListenAdmin(*adminAddr)
+	if err != nil {
+		log.Fatalln("error listening for the admin API:", err)
+	}
+
+	log.Println("serving the admin API on:", *adminAddr)
+
+	var server http.Server
+
+	server.Handler = cache.AdminHandler(func() error {
+		return persist(cache, counter)
+	})
+
+	return &server, listener
+}
+
+func launchAdminServer(server *http.Server, listener net.Listener) {
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		log.Fatalf("admin server Serve error: %v", err)
+	}
+}
+
+// newHTTPSRedirectServer returns the server redirecting plain HTTP requests
+// to the HTTPS port of the main server
+func newHTTPSRedirectServer() *http.Server {
+	_, httpsPort, err := net.SplitHostPort(*address)
+	if err != nil {
+		log.Fatalln("error in listen address:", err)
+	}
+
+	var server http.Server
+
+	server.Addr = *httpsRedirectAddr
+	server.Handler = shorten.HTTPSRedirectHandler(httpsPort)
+
+	return &server
+}
+
+// loadAdminToken loads the admin token file, empty when none is configured.
+// Admin APIs on TCP require a token, any local user could reach them.
+func loadAdminToken() string {
+	if network, _ := shorten.AdminNetwork(*adminAddr); *adminAddr != "" && network != "unix" && *adminTokenFile == "" {
+		log.Fatalln("-admin-token-file is required with a host:port admin address")
+	}
+
+	if *adminTokenFile == "" {
+		return ""
+	}
+
+	token, err := shorten.ReadAdminToken(*adminTokenFile)
+	if err != nil {
+		log.Fatalln("error loading admin token:", err)
+	}
+
+	return token
+}
+
+// loadAPIKeys loads the API keys file, nil when none is configured
+func loadAPIKeys() *shorten.APIKeys {
+	if *apiKeysFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(*apiKeysFile)
+	if err != nil {
+		log.Fatalln("error opening API keys file:", err)
+	}
+	defer file.Close()
+
+	keys, err := shorten.LoadAPIKeys(file)
+	if err != nil {
+		log.Fatalln("error loading API keys:", err)
+	}
+
+	return keys
+}
+
+// rateLimitOptions returns the options of the rate limits enabled by the flags
+func rateLimitOptions() []shorten.Option {
+	options := make([]shorten.Option, 0)
+
+	createLimit := shorten.RateLimit{Rate: *createRate, Burst: *createBurst}
+	redirectLimit := shorten.RateLimit{Rate: *redirectRate, Burst: *redirectBurst}
+
+	if *createBurst < 1 || *redirectBurst < 1 {
+		log.Fatalln("-create-burst and -redirect-burst must be at least 1")
+	}
+
+	if *createRate != 0 {
+		if err := createLimit.Validate(); err != nil {
+			log.Fatalln("error in create rate limit:", err)
+		}
+
+		options = append(options, shorten.WithCreateRateLimit(createLimit))
+	}
+
+	if *redirectRate != 0 {
+		if err := redirectLimit.Validate(); err != nil {
+			log.Fatalln("error in redirect rate limit:", err)
+		}
+
+		options = append(options, shorten.WithRedirectRateLimit(redirectLimit))
+	}
+
+	proxies, err := shorten.ParseTrustedProxies(splitList(*trustedProxies))
+	if err != nil {
+		log.Fatalln("error in trusted proxies:", err)
+	}
+
+	return append(options, shorten.WithTrustedProxies(proxies...))
+}
+
+// middlewares returns the middlewares enabled by the flags, outermost first
+func middlewares() []shorten.Middleware {
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	enabled := make([]shorten.Middleware, 0)
+
+	if *requestID {
+		enabled = append(enabled, shorten.RequestID())
+	}
+
+	if *accessLog {
+		enabled = append(enabled, shorten.AccessLog(logger))
+	}
+
+	if *recoverPanic {
+		enabled = append(enabled, shorten.Recover(logger))
+	}
+
+	return enabled
+}
+
+// splitList splits a comma separated flag value dropping empty items
+func splitList(value string) []string {
+	items := make([]string, 0)
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
 func main() {
 	flag.Parse()
+	resolveWALFile()
 
 	idleConnectionsClosed := make(chan struct{})
 
 	var server http.Server
 	server.Addr = fmt.Sprintf("%s", *address)
 
-	cache := shorten.NewURLShortener()
+	useTLS := configureTLS(&server)
+	if *httpsRedirectAddr != "" && !useTLS {
+		log.Fatalln("-https-redirect-addr requires HTTPS")
+	}
+
+	codeGenerator, counter := newCodeGenerator()
+
+	options := []shorten.Option{
+		shorten.WithCodeGenerator(codeGenerator),
+		shorten.WithAllowedSchemes(splitList(*schemes)...),
+		shorten.WithSelfHosts(splitList(*selfHosts)...),
+		shorten.WithRoutePrefix(*routePrefix),
+	}
+
+	if *sortQuery {
+		options = append(options, shorten.WithSortedQuery())
+	}
 
-	cache.SetupHandlerFunctions()
+	if !shorten.IsRedirectCode(*redirectCode) {
+		log.Fatalln("invalid redirect code:", *redirectCode)
+	}
+
+	options = append(options, shorten.WithRedirectCode(*redirectCode))
+
+	if *passQuery {
+		options = append(options, shorten.WithQueryPassthrough())
+	}
+
+	options = append(options, rateLimitOptions()...)
+
+	if *publicURL != "" {
+		u, err := shorten.ParsePublicURL(*publicURL)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		options = append(options, shorten.WithPublicURL(u))
+	}
+
+	if *trustForwarded {
+		options = append(options, shorten.WithTrustedForwardedHeaders())
+	}
+
+	if keys := loadAPIKeys(); keys != nil {
+		options = append(options, shorten.WithAPIKeys(keys))
+	}
+
+	if token := loadAdminToken(); token != "" {
+		options = append(options, shorten.WithAdminToken(token))
+	}
+
+	wal := openWAL()
+	if wal != nil {
+		defer wal.Close()
+		options = append(options, shorten.WithWAL(wal))
+	}
+
+	cache := shorten.NewURLShortener(options...)
+
+	server.Handler = shorten.Chain(cache, middlewares()...)
 	unpersist(cache)
 
-	go setupHTTPServerShutdown(cache, &server, idleConnectionsClosed)
+	if wal != nil {
+		replay(cache)
+		// fold the replayed log in the snapshot so it does not grow forever
+		persist(cache, counter)
+	}
+
+	stopBackground := make(chan struct{})
+
+	if *snapshotInterval > 0 {
+		go snapshotPeriodically(cache, counter, stopBackground)
+	}
+
+	if *reapInterval > 0 {
+		go reapPeriodically(cache, stopBackground)
+	}
+
+	servers := []*http.Server{&server}
+
+	if *httpsRedirectAddr != "" {
+		redirectServer := newHTTPSRedirectServer()
+		servers = append(servers, redirectServer)
+
+		go launchHTTPServer(redirectServer)
+	}
+
+	if *adminAddr != "" {
+		adminServer, listener := newAdminServer(cache, counter)
+		servers = append(servers, adminServer)
 
-	launchHTTPServer(&server)
+		go launchAdminServer(adminServer, listener)
+	}
+
+	go setupHTTPServerShutdown(cache, counter, servers, stopBackground, idleConnectionsClosed)
+
+	if useTLS {
+		launchHTTPSServer(&server)
+	} else {
+		launchHTTPServer(&server)
+	}
 
 	<-idleConnectionsClosed
 	log.Println("shutdown completed")
@@ -0,0 +1,820 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rgianassi/learning/go/url_shortener/shorten"
+)
+
+// Exit codes, rejected rows tells an import completed with rows not imported
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitRejected = 2
+)
+
+// defaultAdminAddr the admin address of a server started with
+// -admin-addr unix:shortener-admin.sock in the same directory
+const defaultAdminAddr = "unix:shortener-admin.sock"
+
+// adminPageLimit links fetched per request when listing
+const adminPageLimit = 1000
+
+// command a shortenctl subcommand, it returns the exit code
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int
+}
+
+var commands = []command{
+	{"import", "import links from a CSV, JSON Lines or persistence JSON file", runImport},
+	{"export", "export links as CSV, JSON Lines or persistence JSON", runExport},
+	{"list", "list the links of a running server", runList},
+	{"search", "search the links of a running server by code or long URL", runSearch},
+	{"show", "show a link of a running server with its analytics", runShow},
+	{"delete", "delete links of a running server", runDelete},
+	{"alias", "add an alias of a link of a running server", runAlias},
+	{"stats", "dump the statistics of a running server", runStats},
+	{"snapshot", "make a running server persist its links", runSnapshot},
+}
+
+// adminLink a link as served by the admin API
+type adminLink struct {
+	Code         string     `json:"code"`
+	ShortURL     string     `json:"short_url,omitempty"`
+	LongURL      string     `json:"long_url"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+	Expired      bool       `json:"expired,omitempty"`
+	Clicks       int64      `json:"clicks"`
+}
+
+type adminLinksPage struct {
+	Links []adminLink `json:"links"`
+	Total int         `json:"total"`
+}
+
+type countJSON struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// adminLinkDetail a link with its click analytics
+type adminLinkDetail struct {
+	adminLink
+	Analytics struct {
+		FirstAccess   *time.Time  `json:"first_access,omitempty"`
+		LastAccess    *time.Time  `json:"last_access,omitempty"`
+		ClicksPerDay  []countJSON `json:"clicks_per_day"`
+		TopReferrers  []countJSON `json:"top_referrers"`
+		TopUserAgents []countJSON `json:"top_user_agents"`
+	} `json:"analytics"`
+}
+
+type snapshotStats struct {
+	Count          int64 `json:"count"`
+	LastDurationNs int64 `json:"last_duration_ns"`
+	LastSizeBytes  int64 `json:"last_size_bytes"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+// adminClient talks to the admin API of a running server
+type adminClient struct {
+	client    *http.Client
+	baseURL   string
+	tokenFile string
+}
+
+// adminFlags the flags of the commands talking to a running server
+type adminFlags struct {
+	address   *string
+	tokenFile *string
+	asJSON    *bool
+}
+
+func newAdminFlags(flags *flag.FlagSet) adminFlags {
+	return adminFlags{
+		address:   flags.String("admin", defaultAdminAddr, "admin address of the server: unix:PATH for a Unix socket or host:port"),
+		tokenFile: flags.String("admin-token-file", "", "file of the admin token of the server, required with a host:port admin address"),
+		asJSON:    flags.Bool("json", false, "print JSON instead of a table"),
+	}
+}
+
+// newAdminClient returns a client of the admin address sending the token of
+// tokenFile, unless empty
+func newAdminClient(address, tokenFile string) *adminClient {
+	network, address := shorten.AdminNetwork(address)
+
+	var dialer net.Dialer
+
+	transport := http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+
+	client := adminClient{}
+
+	client.client = &http.Client{Transport: &transport, Timeout: time.Minute}
+	client.tokenFile = tokenFile
+	client.baseURL = "http://" + address
+	if network == "unix" {
+		// the server accepts loopback hosts only
+		client.baseURL = "http://localhost"
+	}
+
+	return &client
+}
+
+// do sends a request to the admin API decoding the JSON response in v,
+// unless nil, error responses are returned as errors
+func (a *adminClient) do(method, path string, body, v interface{}) error {
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if a.tokenFile != "" {
+		token, err := shorten.ReadAdminToken(a.tokenFile)
+		if err != nil {
+			return err
+		}
+
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		var apiError errorJSON
+
+		if err := json.NewDecoder(response.Body).Decode(&apiError); err != nil || apiError.Error == "" {
+			return errors.New(response.Status)
+		}
+
+		return errors.New(apiError.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// links fetches the links matching the query, page after page, at most
+// limit unless zero
+func (a *adminClient) links(query url.Values, limit int) ([]adminLink, error) {
+	links := make([]adminLink, 0)
+
+	for {
+		query.Set("offset", fmt.Sprint(len(links)))
+		query.Set("limit", fmt.Sprint(adminPageLimit))
+
+		var page adminLinksPage
+		if err := a.do(http.MethodGet, "/admin/links?"+query.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+
+		links = append(links, page.Links...)
+
+		if limit > 0 && len(links) >= limit {
+			return links[:limit], nil
+		}
+
+		if len(page.Links) == 0 || len(links) >= page.Total {
+			return links, nil
+		}
+	}
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// formatTime formats an optional time for tables
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func printLinks(links []adminLink) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "CODE\tLONG URL\tOWNER\tCREATED\tEXPIRES\tCLICKS")
+
+	for _, link := range links {
+		owner := link.Owner
+		if owner == "" {
+			owner = "-"
+		}
+
+		expires := formatTime(link.ExpiresAt)
+		if link.Expired {
+			expires += " (expired)"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n", link.Code, link.LongURL, owner, formatTime(link.CreatedAt), expires, link.Clicks)
+	}
+
+	w.Flush()
+}
+
+// listLinks runs list and search, q is the search query
+func listLinks(flags *flag.FlagSet, admin adminFlags, args []string, search bool) int {
+	owner := flags.String("owner", "", "only the links of the API key named owner")
+	expired := flags.Bool("expired", false, "include the expired links")
+	limit := flags.Int("limit", 0, "maximum number of links, 0 for all")
+
+	flags.Parse(args)
+
+	query := url.Values{}
+
+	if search {
+		if flags.NArg() != 1 {
+			flags.Usage()
+			return exitFailure
+		}
+
+		query.Set("q", flags.Arg(0))
+	} else if flags.NArg() != 0 {
+		flags.Usage()
+		return exitFailure
+	}
+
+	if *owner != "" {
+		query.Set("owner", *owner)
+	}
+
+	if *expired {
+		query.Set("expired", "true")
+	}
+
+	links, err := newAdminClient(*admin.address, *admin.tokenFile).links(query, *limit)
+	if err != nil {
+		return fail(err)
+	}
+
+	if *admin.asJSON {
+		printJSON(links)
+	} else {
+		printLinks(links)
+	}
+
+	return exitOK
+}
+
+func runList(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	return listLinks(flags, admin, args, false)
+}
+
+func runSearch(args []string) int {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortenctl search [flags] <query>")
+		flags.PrintDefaults()
+	}
+
+	return listLinks(flags, admin, args, true)
+}
+
+func printCounts(w io.Writer, title string, counts []countJSON) {
+	if len(counts) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+
+	for _, c := range counts {
+		fmt.Fprintf(w, "  %s\t%d\n", c.Name, c.Count)
+	}
+}
+
+func runShow(args []string) int {
+	flags := flag.NewFlagSet("show", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortenctl show [flags] <code>")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitFailure
+	}
+
+	var link adminLinkDetail
+
+	if err := newAdminClient(*admin.address, *admin.tokenFile).do(http.MethodGet, "/admin/links/"+url.PathEscape(flags.Arg(0)), nil, &link); err != nil {
+		return fail(err)
+	}
+
+	if *admin.asJSON {
+		printJSON(link)
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Code:\t%s\n", link.Code)
+	if link.ShortURL != "" {
+		fmt.Fprintf(w, "Short URL:\t%s\n", link.ShortURL)
+	}
+	fmt.Fprintf(w, "Long URL:\t%s\n", link.LongURL)
+	fmt.Fprintf(w, "Owner:\t%s\n", link.Owner)
+	fmt.Fprintf(w, "Created:\t%s\n", formatTime(link.CreatedAt))
+	fmt.Fprintf(w, "Expires:\t%s\n", formatTime(link.ExpiresAt))
+	fmt.Fprintf(w, "Expired:\t%t\n", link.Expired)
+	if link.RedirectCode != 0 {
+		fmt.Fprintf(w, "Redirect code:\t%d\n", link.RedirectCode)
+	}
+	fmt.Fprintf(w, "Interstitial:\t%t\n", link.Interstitial)
+	fmt.Fprintf(w, "Clicks:\t%d\n", link.Clicks)
+	fmt.Fprintf(w, "First access:\t%s\n", formatTime(link.Analytics.FirstAccess))
+	fmt.Fprintf(w, "Last access:\t%s\n", formatTime(link.Analytics.LastAccess))
+
+	printCounts(w, "Clicks per day", link.Analytics.ClicksPerDay)
+	printCounts(w, "Top referrers", link.Analytics.TopReferrers)
+	printCounts(w, "Top user agents", link.Analytics.TopUserAgents)
+
+	w.Flush()
+	return exitOK
+}
+
+func runDelete(args []string) int {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	address := flags.String("admin", defaultAdminAddr, "admin address of the server: unix:PATH for a Unix socket or host:port")
+	tokenFile := flags.String("admin-token-file", "", "file of the admin token of the server, required with a host:port admin address")
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortenctl delete [flags] <code>...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitFailure
+	}
+
+	client := newAdminClient(*address, *tokenFile)
+	exitCode := exitOK
+
+	for _, code := range flags.Args() {
+		if err := client.do(http.MethodDelete, "/admin/links/"+url.PathEscape(code), nil, nil); err != nil {
+			exitCode = fail(err)
+			continue
+		}
+
+		fmt.Println("deleted:", code)
+	}
+
+	return exitCode
+}
+
+func runAlias(args []string) int {
+	flags := flag.NewFlagSet("alias", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortenctl alias [flags] <code> <alias>")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitFailure
+	}
+
+	request := map[string]string{"code": flags.Arg(0), "alias": flags.Arg(1)}
+
+	var link adminLink
+
+	if err := newAdminClient(*admin.address, *admin.tokenFile).do(http.MethodPost, "/admin/aliases", request, &link); err != nil {
+		return fail(err)
+	}
+
+	if *admin.asJSON {
+		printJSON(link)
+	} else {
+		printLinks([]adminLink{link})
+	}
+
+	return exitOK
+}
+
+func runStats(args []string) int {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	flags.Parse(args)
+
+	var stats shorten.StatsJSON
+
+	if err := newAdminClient(*admin.address, *admin.tokenFile).do(http.MethodGet, "/admin/stats", nil, &stats); err != nil {
+		return fail(err)
+	}
+
+	if *admin.asJSON {
+		printJSON(&stats)
+	} else {
+		fmt.Print(&stats)
+	}
+
+	return exitOK
+}
+
+func runSnapshot(args []string) int {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	admin := newAdminFlags(flags)
+
+	flags.Parse(args)
+
+	var snapshot snapshotStats
+
+	if err := newAdminClient(*admin.address, *admin.tokenFile).do(http.MethodPost, "/admin/snapshot", nil, &snapshot); err != nil {
+		return fail(err)
+	}
+
+	if *admin.asJSON {
+		printJSON(snapshot)
+		return exitOK
+	}
+
+	fmt.Printf("snapshot taken: %d byte(s) in %s, %d snapshot(s) since start\n",
+		snapshot.LastSizeBytes, time.Duration(snapshot.LastDurationNs), snapshot.Count)
+
+	return exitOK
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: shortenctl <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "run shortenctl <command> -h for the flags of a command")
+}
+
+// fail prints the error and returns the failure exit code
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, "shortenctl:", err)
+	return exitFailure
+}
+
+// fileFormat returns the format flag or, when empty, the format matching the
+// extension of the file
+func fileFormat(format, path string) (shorten.BulkFormat, error) {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+
+	return shorten.ParseBulkFormat(format)
+}
+
+// loadOffline loads the links of a stopped server from its persistence file
+// and write-ahead log, options configure the returned shortener
+func loadOffline(persistence, walFile string, options ...shorten.Option) (*shorten.URLShortener, error) {
+	cache := shorten.NewURLShortener(options...)
+
+	f, err := os.Open(persistence)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		defer f.Close()
+
+		if err := cache.UnpersistFrom(bufio.NewReader(f)); err != nil {
+			return nil, fmt.Errorf("error unpersisting %s: %w", persistence, err)
+		}
+	}
+
+	if walFile == "" {
+		return cache, nil
+	}
+
+	wf, err := os.Open(walFile)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer wf.Close()
+
+	if _, err := cache.ReplayWAL(bufio.NewReader(wf)); err != nil {
+		return nil, fmt.Errorf("error replaying %s: %w", walFile, err)
+	}
+
+	return cache, nil
+}
+
+// walFileOf returns the write-ahead log file of the flags, next to the
+// persistence file unless -wal is given
+func walFileOf(flags *flag.FlagSet, persistence, walFile string) string {
+	given := false
+
+	flags.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "wal"
+	})
+
+	if !given {
+		return persistence + ".wal"
+	}
+
+	return walFile
+}
+
+// newGeneratorConfig declares the flags selecting the code generator of
+// offline imports, they have to match the ones of the server
+func newGeneratorConfig(flags *flag.FlagSet) *shorten.GeneratorConfig {
+	config := shorten.GeneratorConfig{}
+
+	flags.StringVar(&config.Name, "generator", "hash", "short URL generator of the server: hash, counter or random")
+	flags.IntVar(&config.CodeLength, "code-length", 7, "length of the short URLs made by the random generator")
+	flags.StringVar(&config.Alphabet, "alphabet", string(shorten.Base62Alphabet), "characters of the short URLs made by the counter and random generators")
+	flags.BoolVar(&config.ExcludeLookAlikes, "exclude-lookalikes", false, "exclude look-alike characters from the short URLs alphabet")
+	flags.StringVar(&config.CounterFile, "counter-file", "counter.json", "persistence JSON file for the counter generator state")
+
+	return &config
+}
+
+func runImport(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "", "file format: csv, jsonl or json, from the file extension when empty")
+	persistence := flags.String("load", "persistence.json", "persistence JSON file of a stopped server to import into")
+	walFile := flags.String("wal", "", "write-ahead log file of a stopped server, the -load file with a .wal suffix when not given, empty to disable")
+	server := flags.String("server", "", "base URL of a running server to import into through its bulk API, as in http://localhost:9090")
+	apiKey := flags.String("api-key", "", "API key of the running server, when it requires one")
+	generator := newGeneratorConfig(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortenctl import [flags] <file>")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitFailure
+	}
+
+	path := flags.Arg(0)
+
+	bulkFormat, err := fileFormat(*format, path)
+	if err != nil {
+		return fail(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fail(err)
+	}
+	defer f.Close()
+
+	var report shorten.ImportReport
+
+	if *server != "" {
+		report, err = importOnline(*server, *apiKey, bulkFormat, f)
+	} else {
+		report, err = importOffline(*persistence, walFileOf(flags, *persistence, *walFile), *generator, bulkFormat, f)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	printReport(report)
+
+	if len(report.Conflicts) > 0 || len(report.Invalid) > 0 {
+		return exitRejected
+	}
+
+	return exitOK
+}
+
+// importOffline imports into the persistence file of a stopped server, the
+// write-ahead log is folded in the new snapshot and the counter, when
+// generating the codes, saved with it
+func importOffline(persistence, walFile string, generator shorten.GeneratorConfig, format shorten.BulkFormat, r io.Reader) (shorten.ImportReport, error) {
+	codeGenerator, counter, err := shorten.NewCodeGenerator(generator)
+	if err != nil {
+		return shorten.ImportReport{}, err
+	}
+
+	options := []shorten.Option{shorten.WithCodeGenerator(codeGenerator)}
+
+	if walFile != "" {
+		wal, err := shorten.OpenWAL(walFile)
+		if err != nil {
+			return shorten.ImportReport{}, err
+		}
+		defer wal.Close()
+
+		options = append(options, shorten.WithWAL(wal))
+	}
+
+	cache, err := loadOffline(persistence, walFile, options...)
+	if err != nil {
+		return shorten.ImportReport{}, err
+	}
+
+	report, err := cache.Import(bufio.NewReader(r), format)
+	if err != nil {
+		return report, err
+	}
+
+	if err := cache.SnapshotTo(persistence); err != nil {
+		return report, err
+	}
+
+	if counter == nil {
+		return report, nil
+	}
+
+	return report, counter.SnapshotTo(generator.CounterFile)
+}
+
+// importOnline posts the file to the bulk API of a running server
+func importOnline(server, apiKey string, format shorten.BulkFormat, r io.Reader) (shorten.ImportReport, error) {
+	var report shorten.ImportReport
+
+	endpoint, err := url.Parse(strings.TrimSuffix(server, "/") + "/api/bulk")
+	if err != nil {
+		return report, err
+	}
+
+	endpoint.RawQuery = url.Values{"format": {string(format)}}.Encode()
+
+	request, err := http.NewRequest(http.MethodPost, endpoint.String(), r)
+	if err != nil {
+		return report, err
+	}
+
+	if apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return report, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+		return report, fmt.Errorf("%s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&report); err != nil {
+		return report, fmt.Errorf("error decoding import report: %w", err)
+	}
+
+	return report, nil
+}
+
+func printReport(report shorten.ImportReport) {
+	fmt.Printf("imported: %d, unchanged: %d, conflicts: %d, invalid: %d\n",
+		report.Imported, report.Unchanged, len(report.Conflicts), len(report.Invalid))
+
+	for _, issue := range report.Conflicts {
+		fmt.Printf("row %d: conflict: %s\n", issue.Row, issue.Error)
+	}
+
+	for _, issue := range report.Invalid {
+		fmt.Printf("row %d: invalid: %s\n", issue.Row, issue.Error)
+	}
+}
+
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "", "output format: csv, jsonl or json, from the output file extension when empty")
+	persistence := flags.String("load", "persistence.json", "persistence JSON file to export from")
+	walFile := flags.String("wal", "", "write-ahead log file replayed before exporting, the -load file with a .wal suffix when not given, empty to disable")
+	output := flags.String("o", "", "output file, standard output when empty")
+
+	flags.Parse(args)
+
+	if *format == "" && *output == "" {
+		return fail(errors.New("-format is required when writing to standard output"))
+	}
+
+	bulkFormat, err := fileFormat(*format, *output)
+	if err != nil {
+		return fail(err)
+	}
+
+	cache, err := loadOffline(*persistence, walFileOf(flags, *persistence, *walFile))
+	if err != nil {
+		return fail(err)
+	}
+
+	w := os.Stdout
+
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fail(err)
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	writer := bufio.NewWriter(w)
+
+	count, err := cache.Export(writer, bulkFormat)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil && *output != "" {
+		err = w.Close()
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintln(os.Stderr, "links exported:", count)
+	return exitOK
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitFailure)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+
+	usage()
+	os.Exit(exitFailure)
+}
@@ -0,0 +1,90 @@
+package qrcode
+
+// Penalty weights of the mask evaluation rules
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// finderLike the 1:1:3:1:1 finder ratio followed or preceded by 4 light
+// modules, the third mask evaluation rule
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty evaluates the symbol with the four rules of the specification, the
+// mask with the lowest penalty is the one to use
+func (c *Code) penalty() int {
+	penalty := 0
+
+	for i := 0; i < c.Size; i++ {
+		row := func(j int) bool { return c.Black(j, i) }
+		column := func(j int) bool { return c.Black(i, j) }
+
+		penalty += c.linePenalty(row) + c.linePenalty(column)
+	}
+
+	for y := 0; y < c.Size-1; y++ {
+		for x := 0; x < c.Size-1; x++ {
+			dark := c.Black(x, y)
+			if c.Black(x+1, y) == dark && c.Black(x, y+1) == dark && c.Black(x+1, y+1) == dark {
+				penalty += penaltyBlock
+			}
+		}
+	}
+
+	dark := 0
+	for _, module := range c.modules {
+		if module {
+			dark++
+		}
+	}
+
+	deviation := dark*100/len(c.modules) - 50
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	penalty += deviation / 5 * penaltyBalance
+
+	return penalty
+}
+
+// linePenalty evaluates a row or a column with the same color runs and the
+// finder like patterns rules
+func (c *Code) linePenalty(module func(int) bool) int {
+	penalty := 0
+
+	run := 1
+	for j := 1; j <= c.Size; j++ {
+		if j < c.Size && module(j) == module(j-1) {
+			run++
+			continue
+		}
+
+		if run >= 5 {
+			penalty += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	for j := 0; j+len(finderLike[0]) <= c.Size; j++ {
+		for _, pattern := range finderLike {
+			matches := true
+			for k, dark := range pattern {
+				if module(j+k) != dark {
+					matches = false
+					break
+				}
+			}
+
+			if matches {
+				penalty += penaltyFinder
+			}
+		}
+	}
+
+	return penalty
+}
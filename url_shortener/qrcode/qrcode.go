@@ -0,0 +1,439 @@
+// Package qrcode encodes QR codes (ISO/IEC 18004 model 2) in byte mode and
+// renders them as images or SVG documents.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level an error correction level
+type Level int
+
+// Error correction levels, each recovers about the given share of the symbol
+const (
+	Low      Level = iota // 7%
+	Medium                // 15%
+	Quartile              // 25%
+	High                  // 30%
+)
+
+// Errors returned by the encoder
+var (
+	ErrInvalidLevel = errors.New("invalid error correction level")
+	ErrTooLong      = errors.New("data too long for a QR code")
+)
+
+// Version bounds
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+// ParseLevel parses a level from its letter: L, M, Q or H
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+}
+
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// formatBits the level bits of the format information
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Code a QR code symbol
+type Code struct {
+	Version int
+	Level   Level
+	Mask    int
+	Size    int // modules per side
+
+	modules  []bool // row major, dark when true
+	function []bool // modules of the function patterns
+}
+
+// Encode encodes data in the smallest symbol with the error correction level
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLevel, level)
+	}
+
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if bitsNeeded(version, len(data)) <= dataCodewords(version, level)*8 {
+			break
+		}
+	}
+
+	if version > maxVersion {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(data))
+	}
+
+	code := newCode(version, level)
+	code.drawFunctionPatterns()
+	code.drawCodewords(interleave(dataStream(data, version, level), version, level))
+	code.applyBestMask()
+
+	return code, nil
+}
+
+func newCode(version int, level Level) *Code {
+	code := Code{}
+
+	code.Version = version
+	code.Level = level
+	code.Size = version*4 + 17
+	code.modules = make([]bool, code.Size*code.Size)
+	code.function = make([]bool, code.Size*code.Size)
+
+	return &code
+}
+
+// Black tells if the module at column x and row y is dark, the modules out
+// of the symbol are the light quiet zone
+func (c *Code) Black(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.Size || y >= c.Size {
+		return false
+	}
+
+	return c.modules[y*c.Size+x]
+}
+
+// String returns the symbol as text, a line per row with # for dark modules
+func (c *Code) String() string {
+	builder := strings.Builder{}
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				builder.WriteByte('#')
+			} else {
+				builder.WriteByte('.')
+			}
+		}
+		builder.WriteByte('\n')
+	}
+
+	return builder.String()
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y*c.Size+x] = dark
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.set(x, y, dark)
+	c.function[y*c.Size+x] = true
+}
+
+// bitsNeeded the length of the byte mode segment of n bytes
+func bitsNeeded(version, n int) int {
+	return 4 + countBits(version) + n*8
+}
+
+// countBits the length of the character count of byte mode segments
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+
+	return 16
+}
+
+// bitBuffer a sequence of bits packed most significant first
+type bitBuffer struct {
+	bytes []byte
+	n     int
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+
+		if value>>uint(i)&1 == 1 {
+			b.bytes[b.n/8] |= 0x80 >> uint(b.n%8)
+		}
+		b.n++
+	}
+}
+
+// dataStream the data codewords: the byte mode segment, the terminator and
+// the padding up to the symbol capacity
+func dataStream(data []byte, version int, level Level) []byte {
+	capacity := dataCodewords(version, level) * 8
+	buffer := bitBuffer{}
+
+	buffer.append(0x4, 4)
+	buffer.append(len(data), countBits(version))
+	for _, b := range data {
+		buffer.append(int(b), 8)
+	}
+
+	terminator := capacity - buffer.n
+	if terminator > 4 {
+		terminator = 4
+	}
+	buffer.append(0, terminator)
+	buffer.append(0, (8-buffer.n%8)%8)
+
+	for pad := 0xEC; buffer.n < capacity; pad ^= 0xEC ^ 0x11 {
+		buffer.append(pad, 8)
+	}
+
+	return buffer.bytes
+}
+
+// interleave splits the data codewords in blocks, computes their error
+// correction codewords and interleaves them
+func interleave(data []byte, version int, level Level) []byte {
+	blocks := errorCorrectionBlocks[level][version]
+	ecLength := errorCorrectionCodewords[level][version]
+	total := rawCodewords(version)
+	shortBlocks := blocks - total%blocks
+	shortLength := total/blocks - ecLength
+
+	divisor := reedSolomonDivisor(ecLength)
+	dataBlocks := make([][]byte, blocks)
+	ecBlocks := make([][]byte, blocks)
+
+	for i, offset := 0, 0; i < blocks; i++ {
+		length := shortLength
+		if i >= shortBlocks {
+			length++
+		}
+
+		dataBlocks[i] = data[offset : offset+length]
+		ecBlocks[i] = reedSolomonRemainder(dataBlocks[i], divisor)
+		offset += length
+	}
+
+	result := make([]byte, 0, total)
+
+	for i := 0; i <= shortLength; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := 0; i < ecLength; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+// drawFunctionPatterns draws the finder, separator, timing and alignment
+// patterns and reserves the format and version information areas
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the corners taken by the finders
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			c.drawAlignment(x, y)
+		}
+	}
+
+	c.drawFormat(0)
+	c.drawVersion()
+}
+
+// drawFinder draws a finder centered on x, y with its separator
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.Size || yy >= c.Size {
+				continue
+			}
+
+			distance := chebyshev(dx, dy)
+			c.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centered on x, y
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, chebyshev(dx, dy) != 1)
+		}
+	}
+}
+
+func chebyshev(dx, dy int) int {
+	if dx < 0 {
+		dx = -dx
+	}
+
+	if dy < 0 {
+		dy = -dy
+	}
+
+	if dx > dy {
+		return dx
+	}
+
+	return dy
+}
+
+// drawFormat draws both copies of the format information of the mask and
+// the dark module
+func (c *Code) drawFormat(mask int) {
+	bits := formatInformation(c.Level, mask)
+	bit := func(i int) bool {
+		return bits>>uint(i)&1 == 1
+	}
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information, only symbols
+// from version 7 have it
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+
+	bits := versionInformation(c.Version)
+
+	for i := 0; i < 18; i++ {
+		dark := bits>>uint(i)&1 == 1
+		a, b := c.Size-11+i%3, i/3
+
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the two modules wide columns going
+// up and down from the bottom right corner, skipping the function patterns
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		// the vertical timing pattern is skipped as a whole
+		if right == 6 {
+			right = 5
+		}
+
+		upward := (right+1)&2 == 0
+
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if upward {
+				y = c.Size - 1 - vertical
+			}
+
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if c.function[y*c.Size+x] {
+					continue
+				}
+
+				// the remainder bits are light
+				if i < len(codewords)*8 {
+					c.set(x, y, codewords[i/8]>>uint(7-i%8)&1 == 1)
+					i++
+				}
+			}
+		}
+	}
+}
+
+// masks the data masks, a module is flipped when its mask returns true
+var masks = [8]func(x, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+// applyMask flips the data modules selected by the mask, applying it twice
+// restores the symbol
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			i := y*c.Size + x
+			if !c.function[i] && masks[mask](x, y) {
+				c.modules[i] = !c.modules[i]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+
+		c.applyMask(mask)
+	}
+
+	c.Mask = best
+	c.applyMask(best)
+	c.drawFormat(best)
+}
@@ -0,0 +1,127 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantLevel Level
+		wantError error
+	}{
+		{"L", Low, nil},
+		{"m", Medium, nil},
+		{"Q", Quartile, nil},
+		{"h", High, nil},
+		{"", 0, ErrInvalidLevel},
+		{"X", 0, ErrInvalidLevel},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLevel(test.value)
+
+		if !errors.Is(err, test.wantError) {
+			t.Errorf("Incorrect error for %q, got: %v, want: %v.", test.value, err, test.wantError)
+		}
+
+		if got != test.wantLevel {
+			t.Errorf("Incorrect level for %q, got: %v, want: %v.", test.value, got, test.wantLevel)
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	tests := []struct {
+		length      int
+		level       Level
+		wantVersion int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{7, High, 1},
+		{8, High, 2},
+		{122, Medium, 7},
+		{123, Medium, 8},
+		{2953, Low, 40},
+		{1273, High, 40},
+	}
+
+	for _, test := range tests {
+		code, err := Encode(bytes.Repeat([]byte("a"), test.length), test.level)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if code.Version != test.wantVersion {
+			t.Errorf("Incorrect version for %d bytes at %s, got: %v, want: %v.", test.length, test.level, code.Version, test.wantVersion)
+		}
+
+		if code.Size != test.wantVersion*4+17 {
+			t.Errorf("Incorrect size, got: %v, want: %v.", code.Size, test.wantVersion*4+17)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2954), Low); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrTooLong)
+	}
+
+	if _, err := Encode([]byte("a"), Level(4)); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrInvalidLevel)
+	}
+}
+
+func TestEncodeFunctionPatterns(t *testing.T) {
+	code, err := Encode([]byte(strings.Repeat("a", 200)), Medium)
+
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	finder := []string{
+		"#######.",
+		"#.....#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.###.#.",
+		"#.....#.",
+		"#######.",
+		"........",
+	}
+
+	corners := [][2]int{{0, 0}, {code.Size - 8, 0}, {0, code.Size - 8}}
+	for _, corner := range corners {
+		for dy, row := range finder {
+			for dx := range row {
+				// the other corners mirror the first one
+				x, y := dx, dy
+				if corner[0] > 0 {
+					x = 7 - dx
+				}
+				if corner[1] > 0 {
+					y = 7 - dy
+				}
+
+				if got := code.Black(corner[0]+x, corner[1]+y); got != (row[dx] == '#') {
+					t.Errorf("Incorrect finder module at %d, %d, got: %v, want: %v.", corner[0]+x, corner[1]+y, got, !got)
+				}
+			}
+		}
+	}
+
+	for i := 8; i < code.Size-8; i++ {
+		if code.Black(i, 6) != (i%2 == 0) || code.Black(6, i) != (i%2 == 0) {
+			t.Errorf("Incorrect timing module at %d.", i)
+		}
+	}
+
+	if !code.Black(8, code.Size-8) {
+		t.Errorf("Missing dark module.")
+	}
+}
@@ -0,0 +1,57 @@
+package qrcode
+
+// gfPolynomial the primitive polynomial of GF(256) used by QR codes:
+// x^8 + x^4 + x^3 + x^2 + 1
+const gfPolynomial = 0x11D
+
+// gfMultiply multiplies two elements of GF(256)
+func gfMultiply(x, y byte) byte {
+	result := 0
+
+	for i := 7; i >= 0; i-- {
+		result = result<<1 ^ (result>>7)*gfPolynomial
+		result ^= int(y>>uint(i)&1) * int(x)
+	}
+
+	return byte(result)
+}
+
+// reedSolomonDivisor the generator polynomial of degree n, the product of
+// (x - 2^i) for i from 0 to n-1, without its leading 1 coefficient and with
+// the highest power coefficients first
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+
+		root = gfMultiply(root, 2)
+	}
+
+	return result
+}
+
+// reedSolomonRemainder the error correction codewords of data, the
+// remainder of its division by the divisor
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,36 @@
+package qrcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGFMultiply(t *testing.T) {
+	tests := []struct {
+		x, y       byte
+		wantResult byte
+	}{
+		{0, 0x53, 0},
+		{1, 0x53, 0x53},
+		{2, 0x80, 0x1D},
+		{0x8E, 2, 1},
+	}
+
+	for _, test := range tests {
+		if got := gfMultiply(test.x, test.y); got != test.wantResult {
+			t.Errorf("Incorrect product of %#x and %#x, got: %#x, want: %#x.", test.x, test.y, got, test.wantResult)
+		}
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// HELLO WORLD in a version 1-Q symbol
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	want := []byte{168, 72, 22, 82, 217, 54, 156, 0, 46, 15, 180, 122, 16}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(len(want)))
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("Incorrect error correction codewords, got: %v, want: %v.", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package qrcode
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// QuietZone the light border around the symbol in modules
+const QuietZone = 4
+
+// palette light then dark
+var palette = color.Palette{color.White, color.Black}
+
+// Modules returns the modules per side of the rendered symbol, quiet zone
+// included
+func (c *Code) Modules() int {
+	return c.Size + 2*QuietZone
+}
+
+// Image returns the symbol with its quiet zone, scale pixels per module
+func (c *Code) Image(scale int) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+
+	side := c.Modules() * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), palette)
+
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			if c.Black(x/scale-QuietZone, y/scale-QuietZone) {
+				img.Pix[y*img.Stride+x] = 1
+			}
+		}
+	}
+
+	return img
+}
+
+// WriteSVG writes the symbol with its quiet zone as a SVG document of size
+// pixels per side, the dark modules of each row are joined in a single path
+func (c *Code) WriteSVG(w io.Writer, size int) error {
+	bw := bufio.NewWriter(w)
+	modules := c.Modules()
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#fff"/>`, modules, modules)
+	bw.WriteString(`<path fill="#000" d="`)
+
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; {
+			if !c.Black(x, y) {
+				x++
+				continue
+			}
+
+			run := 1
+			for c.Black(x+run, y) {
+				run++
+			}
+
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", x+QuietZone, y+QuietZone, run, run)
+			x += run
+		}
+	}
+
+	bw.WriteString(`"/></svg>`)
+	bw.WriteString("\n")
+
+	return bw.Flush()
+}
@@ -0,0 +1,147 @@
+package qrcode
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+var goldenTests = []struct {
+	name  string
+	data  string
+	level Level
+}{
+	{"short-link-L", "http://localhost:9090/4611ce1", Low},
+	{"short-link-H", "http://localhost:9090/4611ce1", High},
+	{"long-link-M", "https://links.example.com/go/powersoft-hmi-release-notes-2020", Medium},
+}
+
+// golden compares got with the golden file, rewriting it with -update
+func golden(t *testing.T, name string, got []byte) []byte {
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	return want
+}
+
+func TestImageGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		code, err := Encode([]byte(test.data), test.level)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		buffer := bytes.Buffer{}
+		if err := png.Encode(&buffer, code.Image(4)); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		want, err := png.Decode(bytes.NewReader(golden(t, test.name+".png", buffer.Bytes())))
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		got := code.Image(4)
+
+		if got.Bounds() != want.Bounds() {
+			t.Errorf("Incorrect %s bounds, got: %v, want: %v.", test.name, got.Bounds(), want.Bounds())
+			continue
+		}
+
+		if !sameImage(got, want) {
+			t.Errorf("Incorrect %s image, run the tests with -update and inspect testdata/%s.png.", test.name, test.name)
+		}
+	}
+}
+
+func sameImage(got, want image.Image) bool {
+	bounds := got.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestSVGGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		code, err := Encode([]byte(test.data), test.level)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		buffer := bytes.Buffer{}
+		if err := code.WriteSVG(&buffer, 256); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if want := golden(t, test.name+".svg", buffer.Bytes()); !bytes.Equal(buffer.Bytes(), want) {
+			t.Errorf("Incorrect %s SVG, got: %s, want: %s.", test.name, buffer.Bytes(), want)
+		}
+	}
+}
+
+func TestTextGolden(t *testing.T) {
+	for _, test := range goldenTests {
+		code, err := Encode([]byte(test.data), test.level)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		got := code.String()
+
+		if want := golden(t, test.name+".txt", []byte(got)); got != string(want) {
+			t.Errorf("Incorrect %s modules, got:\n%s\nwant:\n%s", test.name, got, want)
+		}
+	}
+}
+
+func TestImageScale(t *testing.T) {
+	code, err := Encode([]byte("http://localhost:9090/4611ce1"), Medium)
+
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	img := code.Image(3)
+	side := code.Modules() * 3
+
+	if img.Bounds().Dx() != side || img.Bounds().Dy() != side {
+		t.Errorf("Incorrect image size, got: %v, want: %v.", img.Bounds().Size(), side)
+	}
+
+	// the top left finder starts after the quiet zone
+	quietZone := QuietZone * 3
+	if img.ColorIndexAt(quietZone-1, quietZone) != 0 || img.ColorIndexAt(quietZone, quietZone) != 1 {
+		t.Errorf("Incorrect quiet zone.")
+	}
+}
@@ -0,0 +1,86 @@
+package qrcode
+
+// errorCorrectionCodewords the error correction codewords of each block by
+// level and version, version 0 is unused
+var errorCorrectionCodewords = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// errorCorrectionBlocks the number of blocks by level and version, version 0
+// is unused
+var errorCorrectionBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// rawCodewords the codewords a symbol holds: its modules less the function
+// patterns and the remainder bits, divided by 8
+func rawCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+
+	if version >= 2 {
+		alignments := version/7 + 2
+		modules -= (25*alignments-10)*alignments - 55
+
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+
+	return modules / 8
+}
+
+// dataCodewords the data codewords a symbol holds at the level
+func dataCodewords(version int, level Level) int {
+	return rawCodewords(version) - errorCorrectionCodewords[level][version]*errorCorrectionBlocks[level][version]
+}
+
+// alignmentPositions the row and column coordinates of the alignment pattern
+// centers, evenly spaced from the bottom right but the first one
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	size := version*4 + 17
+
+	positions := make([]int, count)
+	positions[0] = 6
+
+	for i, position := count-1, size-7; i >= 1; i, position = i-1, position-step {
+		positions[i] = position
+	}
+
+	return positions
+}
+
+// formatInformation the 15 bits of the level and the mask protected by a
+// BCH(15, 5) code and masked so they are never all light
+func formatInformation(level Level, mask int) int {
+	data := level.formatBits()<<3 | mask
+
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+
+	return (data<<10 | remainder) ^ 0x5412
+}
+
+// versionInformation the 18 bits of the version protected by a BCH(18, 6)
+// code
+func versionInformation(version int) int {
+	remainder := version
+	for i := 0; i < 12; i++ {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1F25
+	}
+
+	return version<<12 | remainder
+}
@@ -0,0 +1,83 @@
+package qrcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDataCodewords(t *testing.T) {
+	tests := []struct {
+		version    int
+		level      Level
+		wantResult int
+	}{
+		{1, Low, 19},
+		{1, High, 9},
+		{5, Quartile, 62},
+		{10, Medium, 216},
+		{40, Low, 2956},
+		{40, High, 1276},
+	}
+
+	for _, test := range tests {
+		if got := dataCodewords(test.version, test.level); got != test.wantResult {
+			t.Errorf("Incorrect data codewords of %d-%s, got: %v, want: %v.", test.version, test.level, got, test.wantResult)
+		}
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	tests := []struct {
+		version    int
+		wantResult []int
+	}{
+		{1, nil},
+		{2, []int{6, 18}},
+		{7, []int{6, 22, 38}},
+		{22, []int{6, 26, 50, 74, 98}},
+		{32, []int{6, 34, 60, 86, 112, 138}},
+		{40, []int{6, 30, 58, 86, 114, 142, 170}},
+	}
+
+	for _, test := range tests {
+		if got := alignmentPositions(test.version); !reflect.DeepEqual(got, test.wantResult) {
+			t.Errorf("Incorrect alignment positions of version %d, got: %v, want: %v.", test.version, got, test.wantResult)
+		}
+	}
+}
+
+func TestFormatInformation(t *testing.T) {
+	tests := []struct {
+		level      Level
+		mask       int
+		wantResult int
+	}{
+		{Low, 0, 0x77C4},
+		{Medium, 0, 0x5412},
+		{Quartile, 0, 0x355F},
+		{High, 7, 0x083B},
+	}
+
+	for _, test := range tests {
+		if got := formatInformation(test.level, test.mask); got != test.wantResult {
+			t.Errorf("Incorrect format information of %s mask %d, got: %015b, want: %015b.", test.level, test.mask, got, test.wantResult)
+		}
+	}
+}
+
+func TestVersionInformation(t *testing.T) {
+	tests := []struct {
+		version    int
+		wantResult int
+	}{
+		{7, 0x07C94},
+		{21, 0x15683},
+		{40, 0x28C69},
+	}
+
+	for _, test := range tests {
+		if got := versionInformation(test.version); got != test.wantResult {
+			t.Errorf("Incorrect version information of version %d, got: %018b, want: %018b.", test.version, got, test.wantResult)
+		}
+	}
+}
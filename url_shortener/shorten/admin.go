@@ -0,0 +1,487 @@
+package shorten
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Admin API routes, served by AdminHandler on its own listener
+const (
+	adminLinksRoute    = "/admin/links"
+	adminAliasesRoute  = "/admin/aliases"
+	adminStatsRoute    = "/admin/stats"
+	adminSnapshotRoute = "/admin/snapshot"
+)
+
+// unixAddressPrefix marks an admin address as the path of a Unix socket
+const unixAddressPrefix = "unix:"
+
+// Errors of the admin API: ErrSnapshotDisabled is returned when no snapshot
+// function was given, ErrAdminNotLocal when listening beyond the loopback
+// and ErrEmptyAdminToken reading a token file without token
+var (
+	ErrSnapshotDisabled = errors.New("snapshots not enabled")
+	ErrAdminNotLocal    = errors.New("admin address not local")
+	ErrEmptyAdminToken  = errors.New("empty admin token")
+)
+
+// adminLinkJSON a link as seen by the admin API, whatever its owner and
+// expired ones included. The short URL is only known with a public URL, the
+// admin listener is not reachable by the clients of the links.
+type adminLinkJSON struct {
+	Code         string     `json:"code"`
+	ShortURL     string     `json:"short_url,omitempty"`
+	LongURL      string     `json:"long_url"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+	Expired      bool       `json:"expired,omitempty"`
+	Clicks       int64      `json:"clicks"`
+}
+
+type adminLinksPageJSON struct {
+	Links  []adminLinkJSON `json:"links"`
+	Total  int             `json:"total"`
+	Offset int             `json:"offset"`
+	Limit  int             `json:"limit"`
+}
+
+// adminLinkDetailJSON a link with its click analytics
+type adminLinkDetailJSON struct {
+	adminLinkJSON
+	Analytics linkStatsJSON `json:"analytics"`
+}
+
+type createAliasJSON struct {
+	Code  string `json:"code"`
+	Alias string `json:"alias"`
+}
+
+// AdminNetwork splits an admin address in the network and address to listen
+// on or dial: unix:PATH is a Unix socket, anything else a TCP address
+func AdminNetwork(address string) (string, string) {
+	if strings.HasPrefix(address, unixAddressPrefix) {
+		return "unix", strings.TrimPrefix(address, unixAddressPrefix)
+	}
+
+	return "tcp", address
+}
+
+// checkLoopback checks a TCP address is on the loopback interface only:
+// localhost or a host whose addresses are all loopback ones
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(host, "localhost") {
+		return nil
+	}
+
+	if host == "" {
+		return fmt.Errorf("%w: %s", ErrAdminNotLocal, address)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return err
+		}
+	}
+
+	if len(ips) == 0 {
+		return fmt.Errorf("%w: %s", ErrAdminNotLocal, address)
+	}
+
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return fmt.Errorf("%w: %s", ErrAdminNotLocal, address)
+		}
+	}
+
+	return nil
+}
+
+// ReadAdminToken reads the token of the admin API from a file, surrounding
+// white space is dropped
+func ReadAdminToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("%w: %s", ErrEmptyAdminToken, path)
+	}
+
+	return token, nil
+}
+
+// ListenAdmin listens on an admin address, TCP addresses must be on the
+// loopback interface and are meant to be served with an admin token, as
+// any local user can reach them. A stale Unix socket left by a crash is
+// removed first and the socket is made accessible to its owner only.
+func ListenAdmin(address string) (net.Listener, error) {
+	network, address := AdminNetwork(address)
+
+	if network != "unix" {
+		if err := checkLoopback(address); err != nil {
+			return nil, err
+		}
+
+		return net.Listen(network, address)
+	}
+
+	if info, err := os.Stat(address); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial(network, address); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("admin socket in use: %s", address)
+		}
+
+		os.Remove(address)
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(address, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// AdminHandler returns the handler of the admin API: listing, searching,
+// showing, deleting and aliasing every link, the server statistics and
+// snapshots taken with the snapshot function, nil to disable them. Requests
+// must name a loopback host and carry the admin token, when set with
+// WithAdminToken: serve it on a local listener only.
+func (c *URLShortener) AdminHandler(snapshot func() error) http.Handler {
+	router := http.NewServeMux()
+
+	router.HandleFunc(adminLinksRoute, c.adminLinksHandler)
+	router.HandleFunc(adminLinksRoute+"/", c.adminLinksHandler)
+	router.HandleFunc(adminAliasesRoute, c.adminAliasesHandler)
+	router.HandleFunc(adminStatsRoute, c.adminStatsHandler)
+	router.HandleFunc(adminSnapshotRoute, func(w http.ResponseWriter, r *http.Request) {
+		c.adminSnapshotHandler(w, r, snapshot)
+	})
+
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSONError(w, http.StatusNotFound, "not found")
+	})
+
+	return c.adminGuard(router)
+}
+
+// isLoopbackHost tells if the Host header of a request names localhost or a
+// loopback address
+func isLoopbackHost(host string) bool {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminGuard refuses the requests naming other hosts, as web pages could
+// reach a local listener renaming their own host through DNS rebinding, and
+// the requests without the admin token when set
+func (c *URLShortener) adminGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			writeJSONError(w, http.StatusForbidden, fmt.Sprintf("host not allowed: %s", r.Host))
+			return
+		}
+
+		if c.adminToken == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+		token := strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix))
+
+		if !strings.HasPrefix(authorization, bearerPrefix) || subtle.ConstantTimeCompare([]byte(token), []byte(c.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="shortener-admin"`)
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireJSON answers 415 to requests whose body is not JSON, as browsers
+// send other media types across sites without asking first
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == mediaTypeJSON {
+		return true
+	}
+
+	writeJSONError(w, http.StatusUnsupportedMediaType, fmt.Sprintf("media type not supported: %q, want: %s", mediaType, mediaTypeJSON))
+	return false
+}
+
+func (c *URLShortener) newAdminLinkJSON(r *http.Request, shortURL string, link Link, now time.Time) adminLinkJSON {
+	admin := adminLinkJSON{
+		Code:         shortURL,
+		LongURL:      link.URL,
+		CreatedAt:    optionalTime(link.CreatedAt),
+		ExpiresAt:    optionalTime(link.ExpiresAt),
+		Owner:        link.Owner,
+		RedirectCode: link.RedirectCode,
+		Interstitial: link.Interstitial,
+		Expired:      link.isExpired(now),
+		Clicks:       c.analytics.clicks(shortURL),
+	}
+
+	if c.publicURL != nil {
+		admin.ShortURL = c.shortLink(r, shortURL)
+	}
+
+	return admin
+}
+
+// adminLinksHandler serves the links collection and each link below it
+func (c *URLShortener) adminLinksHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, adminLinksRoute), "/")
+
+	switch {
+	case code == "" && r.Method == http.MethodGet:
+		c.adminListLinks(w, r)
+	case code == "":
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case strings.Contains(code, "/"):
+		writeJSONError(w, http.StatusNotFound, "not found")
+	case r.Method == http.MethodGet:
+		c.adminShowLink(w, r, code)
+	case r.Method == http.MethodDelete:
+		c.adminDeleteLink(w, code)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// matchesSearch tells if the link is one searched: q is a case insensitive
+// substring of the code or of the long URL, owner its exact owner
+func matchesSearch(shortURL string, link Link, q, owner string) bool {
+	if owner != "" && link.Owner != owner {
+		return false
+	}
+
+	q = strings.ToLower(q)
+
+	return strings.Contains(strings.ToLower(shortURL), q) || strings.Contains(strings.ToLower(link.URL), q)
+}
+
+// adminListLinks writes a page of the links sorted by code matching the q
+// and owner query parameters, the expired ones only when asked with
+// expired=true
+func (c *URLShortener) adminListLinks(w http.ResponseWriter, r *http.Request) {
+	offset, err := parsePageParameter(r, "offset", 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := parsePageParameter(r, "limit", maxPageLimit)
+	if err != nil || limit == 0 || limit > maxPageLimit {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+		return
+	}
+
+	query := r.URL.Query()
+	q, owner := query.Get("q"), query.Get("owner")
+	withExpired := query.Get("expired") == "true"
+
+	links := make([]adminLinkJSON, 0)
+
+	now := c.now()
+
+	err = c.store.Iterate(func(shortURL string, link Link) bool {
+		if (withExpired || !link.isExpired(now)) && matchesSearch(shortURL, link, q, owner) {
+			links = append(links, c.newAdminLinkJSON(r, shortURL, link, now))
+		}
+		return true
+	})
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Code < links[j].Code
+	})
+
+	page := adminLinksPageJSON{Total: len(links), Offset: offset, Limit: limit}
+
+	if offset > len(links) {
+		offset = len(links)
+	}
+
+	end := offset + limit
+	if end > len(links) {
+		end = len(links)
+	}
+
+	page.Links = links[offset:end]
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// adminShowLink writes a link with its analytics, expired links included
+func (c *URLShortener) adminShowLink(w http.ResponseWriter, r *http.Request, shortURL string) {
+	link, err := c.store.Get(shortURL)
+
+	if err == ErrNotFound {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s: %s", ErrNotFound, shortURL))
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	detail := adminLinkDetailJSON{
+		adminLinkJSON: c.newAdminLinkJSON(r, shortURL, link, c.now()),
+		Analytics:     c.analytics.snapshot(shortURL),
+	}
+
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (c *URLShortener) adminDeleteLink(w http.ResponseWriter, shortURL string) {
+	err := c.deleteURL(shortURL)
+
+	if err == ErrNotFound {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s: %s", ErrNotFound, shortURL))
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminAliasesHandler registers an alias of an existing link: the alias gets
+// the same long URL, owner, expiry and redirect options
+func (c *URLShortener) adminAliasesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !requireJSON(w, r) {
+		return
+	}
+
+	var request createAliasJSON
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize)).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %s", err))
+		return
+	}
+
+	link, err := c.GetLink(request.Code)
+
+	if errors.Is(err, ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if errors.Is(err, ErrExpired) {
+		writeJSONError(w, http.StatusGone, err.Error())
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if _, err := c.aliasURL(request.Alias, link); err != nil {
+		writeJSONError(w, shortenErrorStatus(err), err.Error())
+		return
+	}
+
+	aliased, err := c.GetLink(request.Alias)
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, c.newAdminLinkJSON(r, request.Alias, aliased, c.now()))
+}
+
+func (c *URLShortener) adminStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &c.statistics)
+}
+
+// adminSnapshotHandler takes a snapshot and writes the snapshot statistics
+func (c *URLShortener) adminSnapshotHandler(w http.ResponseWriter, r *http.Request, snapshot func() error) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !requireJSON(w, r) {
+		return
+	}
+
+	if snapshot == nil {
+		writeJSONError(w, http.StatusNotImplemented, ErrSnapshotDisabled.Error())
+		return
+	}
+
+	if err := snapshot(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c.statistics.lastSnapshot())
+}
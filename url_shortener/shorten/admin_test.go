@@ -0,0 +1,332 @@
+package shorten
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveAdmin(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Host = "localhost"
+	request.Header.Set("Content-Type", "application/json")
+	responseRecorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	return responseRecorder
+}
+
+func newAdminTestShortener() *URLShortener {
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	sut.putLink("rome", Link{URL: "https://wttr.in/Rome", Owner: "alice"})
+	sut.putLink("milan", Link{URL: "https://wttr.in/Milan", Owner: "bob"})
+	sut.putLink("turin", Link{URL: "https://wttr.in/Turin", ExpiresAt: sut.now().Add(-time.Hour)})
+
+	return sut
+}
+
+func TestAdminNetwork(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+	}{
+		{"unix:/run/shortener.sock", "unix", "/run/shortener.sock"},
+		{"unix:admin.sock", "unix", "admin.sock"},
+		{"localhost:9091", "tcp", "localhost:9091"},
+	}
+
+	for _, test := range tests {
+		network, address := AdminNetwork(test.address)
+
+		if network != test.wantNetwork || address != test.wantAddress {
+			t.Errorf("Incorrect network of %s, got: %s %s, want: %s %s.", test.address, network, address, test.wantNetwork, test.wantAddress)
+		}
+	}
+}
+
+func TestListenAdmin(t *testing.T) {
+	address := "unix:" + filepath.Join(t.TempDir(), "admin.sock")
+
+	listener, err := ListenAdmin(address)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if _, err := ListenAdmin(address); err == nil {
+		t.Errorf("Expected an error listening on a socket in use but got none.")
+	}
+
+	unixListener := listener.(*net.UnixListener)
+	unixListener.SetUnlinkOnClose(false)
+	unixListener.Close()
+
+	listener, err = ListenAdmin(address)
+	if err != nil {
+		t.Fatalf("Unexpected error on a stale socket but got: %s.", err)
+	}
+	defer listener.Close()
+}
+
+func TestListenAdminLoopbackOnly(t *testing.T) {
+	tests := []struct {
+		address   string
+		wantError error
+	}{
+		{"127.0.0.1:0", nil},
+		{"localhost:0", nil},
+		{":0", ErrAdminNotLocal},
+		{"0.0.0.0:0", ErrAdminNotLocal},
+		{"[::]:0", ErrAdminNotLocal},
+		{"192.0.2.1:0", ErrAdminNotLocal},
+	}
+
+	for _, test := range tests {
+		listener, err := ListenAdmin(test.address)
+
+		if !errors.Is(err, test.wantError) {
+			t.Errorf("Incorrect error for %s, got: %v, want: %v.", test.address, err, test.wantError)
+		}
+
+		if err == nil {
+			listener.Close()
+		}
+	}
+}
+
+func TestAdminListLinks(t *testing.T) {
+	sut := newAdminTestShortener()
+	handler := sut.AdminHandler(nil)
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantCodes  []string
+	}{
+		{"/admin/links", http.StatusOK, []string{"milan", "rome"}},
+		{"/admin/links?expired=true", http.StatusOK, []string{"milan", "rome", "turin"}},
+		{"/admin/links?q=ROME", http.StatusOK, []string{"rome"}},
+		{"/admin/links?q=wttr.in/mil", http.StatusOK, []string{"milan"}},
+		{"/admin/links?owner=alice", http.StatusOK, []string{"rome"}},
+		{"/admin/links?limit=1&offset=1", http.StatusOK, []string{"rome"}},
+		{"/admin/links?limit=0", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAdmin(handler, "GET", test.target, "")
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.target, responseRecorder.Code, test.wantStatus)
+		}
+
+		if test.wantCodes == nil {
+			continue
+		}
+
+		var page adminLinksPageJSON
+		if err := json.Unmarshal(responseRecorder.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		codes := make([]string, 0)
+		for _, link := range page.Links {
+			codes = append(codes, link.Code)
+		}
+
+		if strings.Join(codes, ",") != strings.Join(test.wantCodes, ",") {
+			t.Errorf("Incorrect codes for %s, got: %v, want: %v.", test.target, codes, test.wantCodes)
+		}
+	}
+}
+
+func TestAdminShowAndDeleteLink(t *testing.T) {
+	sut := newAdminTestShortener()
+	handler := sut.AdminHandler(nil)
+
+	request := httptest.NewRequest("GET", "http://localhost:9090/rome", nil)
+	sut.analytics.record("rome", request, sut.now())
+
+	responseRecorder := serveAdmin(handler, "GET", "/admin/links/rome", "")
+
+	var detail adminLinkDetailJSON
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if detail.LongURL != "https://wttr.in/Rome" || detail.Owner != "alice" || detail.Clicks != 1 || detail.Analytics.Clicks != 1 {
+		t.Errorf("Incorrect link, got: %+v.", detail)
+	}
+
+	if detail.ShortURL != "" {
+		t.Errorf("Incorrect short URL without a public URL, got: %s, want: none.", detail.ShortURL)
+	}
+
+	tests := []struct {
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"GET", "/admin/links/turin", http.StatusOK},
+		{"GET", "/admin/links/naples", http.StatusNotFound},
+		{"PUT", "/admin/links/rome", http.StatusMethodNotAllowed},
+		{"POST", "/admin/links", http.StatusMethodNotAllowed},
+		{"DELETE", "/admin/links/rome", http.StatusNoContent},
+		{"DELETE", "/admin/links/rome", http.StatusNotFound},
+		{"GET", "/admin/unknown", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAdmin(handler, test.method, test.target, "")
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s %s, got: %v, want: %v.", test.method, test.target, responseRecorder.Code, test.wantStatus)
+		}
+	}
+}
+
+func TestAdminAliases(t *testing.T) {
+	sut := newAdminTestShortener()
+	handler := sut.AdminHandler(nil)
+
+	tests := []struct {
+		body       string
+		wantStatus int
+	}{
+		{`{"code":"rome","alias":"roma"}`, http.StatusCreated},
+		{`{"code":"rome","alias":"roma"}`, http.StatusCreated},
+		{`{"code":"rome","alias":"milan"}`, http.StatusConflict},
+		{`{"code":"rome","alias":"x"}`, http.StatusBadRequest},
+		{`{"code":"naples","alias":"napoli"}`, http.StatusNotFound},
+		{`{"code":"turin","alias":"torino"}`, http.StatusGone},
+		{`{"code":`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAdmin(handler, "POST", "/admin/aliases", test.body)
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.body, responseRecorder.Code, test.wantStatus)
+		}
+	}
+
+	link, err := sut.GetLink("roma")
+	if err != nil || link.URL != "https://wttr.in/Rome" || link.Owner != "alice" {
+		t.Errorf("Incorrect alias, got: %v, %v.", link, err)
+	}
+}
+
+func TestAdminStatsAndSnapshot(t *testing.T) {
+	sut := newAdminTestShortener()
+
+	responseRecorder := serveAdmin(sut.AdminHandler(nil), "GET", "/admin/stats", "")
+
+	var stats StatsJSON
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &stats); err != nil || stats.ServerStats.TotalURL != 3 {
+		t.Errorf("Incorrect statistics, got: %+v, %v.", stats.ServerStats, err)
+	}
+
+	if responseRecorder := serveAdmin(sut.AdminHandler(nil), "POST", "/admin/snapshot", ""); responseRecorder.Code != http.StatusNotImplemented {
+		t.Errorf("Unexpected status code without snapshots, got: %v, want: %v.", responseRecorder.Code, http.StatusNotImplemented)
+	}
+
+	path := filepath.Join(t.TempDir(), "persistence.json")
+	handler := sut.AdminHandler(func() error {
+		return sut.SnapshotTo(path)
+	})
+
+	responseRecorder = serveAdmin(handler, "POST", "/admin/snapshot", "")
+
+	var snapshots snapshotsJSON
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &snapshots); err != nil || snapshots.Count != 1 || snapshots.LastSizeBytes == 0 {
+		t.Errorf("Incorrect snapshot statistics, got: %+v, %v.", snapshots, err)
+	}
+
+	if data, err := ioutil.ReadFile(path); err != nil || !strings.Contains(string(data), "wttr.in/Rome") {
+		t.Errorf("Incorrect snapshot, got: %s, %v.", data, err)
+	}
+
+	failing := sut.AdminHandler(func() error {
+		return errors.New("disk full")
+	})
+
+	if responseRecorder := serveAdmin(failing, "POST", "/admin/snapshot", ""); responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status code on failure, got: %v, want: %v.", responseRecorder.Code, http.StatusInternalServerError)
+	}
+
+	if responseRecorder := serveAdmin(handler, "GET", "/admin/snapshot", ""); responseRecorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestAdminGuard(t *testing.T) {
+	sut := NewURLShortener(WithAdminToken("4dm1n-s3cr3t"))
+	handler := sut.AdminHandler(func() error {
+		return nil
+	})
+
+	tests := []struct {
+		method        string
+		target        string
+		host          string
+		authorization string
+		contentType   string
+		wantStatus    int
+	}{
+		{"GET", "/admin/stats", "localhost", "Bearer 4dm1n-s3cr3t", "", http.StatusOK},
+		{"GET", "/admin/stats", "127.0.0.1:9091", "Bearer 4dm1n-s3cr3t", "", http.StatusOK},
+		{"GET", "/admin/stats", "[::1]:9091", "Bearer 4dm1n-s3cr3t", "", http.StatusOK},
+		{"GET", "/admin/stats", "rebound.example:9091", "Bearer 4dm1n-s3cr3t", "", http.StatusForbidden},
+		{"GET", "/admin/stats", "192.0.2.1", "Bearer 4dm1n-s3cr3t", "", http.StatusForbidden},
+		{"GET", "/admin/stats", "localhost", "", "", http.StatusUnauthorized},
+		{"GET", "/admin/stats", "localhost", "Bearer wrong", "", http.StatusUnauthorized},
+		{"GET", "/admin/stats", "localhost", "4dm1n-s3cr3t", "", http.StatusUnauthorized},
+		{"POST", "/admin/snapshot", "localhost", "Bearer 4dm1n-s3cr3t", "application/json", http.StatusOK},
+		{"POST", "/admin/snapshot", "localhost", "Bearer 4dm1n-s3cr3t", "text/plain", http.StatusUnsupportedMediaType},
+		{"POST", "/admin/aliases", "localhost", "Bearer 4dm1n-s3cr3t", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.target, nil)
+		request.Host = test.host
+		request.Header.Set("Authorization", test.authorization)
+		request.Header.Set("Content-Type", test.contentType)
+		responseRecorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s %s on %s, got: %v, want: %v.", test.method, test.target, test.host, responseRecorder.Code, test.wantStatus)
+		}
+	}
+}
+
+func TestReadAdminToken(t *testing.T) {
+	dir := t.TempDir()
+
+	tokenPath := filepath.Join(dir, "admin-token")
+	ioutil.WriteFile(tokenPath, []byte("4dm1n-s3cr3t\n"), 0600)
+
+	token, err := ReadAdminToken(tokenPath)
+	if err != nil || token != "4dm1n-s3cr3t" {
+		t.Errorf("Incorrect token, got: %q, %v, want: %q.", token, err, "4dm1n-s3cr3t")
+	}
+
+	emptyPath := filepath.Join(dir, "empty")
+	ioutil.WriteFile(emptyPath, []byte("\n"), 0600)
+
+	if _, err := ReadAdminToken(emptyPath); !errors.Is(err, ErrEmptyAdminToken) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrEmptyAdminToken)
+	}
+}
@@ -0,0 +1,87 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Alias length bounds
+const (
+	minAliasLength = 3
+	maxAliasLength = 64
+)
+
+// aliasCharacters are the characters allowed in a vanity alias
+const aliasCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+
+// Errors returned when registering a vanity alias
+var (
+	ErrInvalidAlias  = errors.New("invalid alias")
+	ErrReservedAlias = errors.New("reserved alias")
+	ErrAliasTaken    = errors.New("alias already taken")
+)
+
+// validateAlias checks the alias charset and length
+func validateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+
+	for _, character := range alias {
+		if !strings.ContainsRune(aliasCharacters, character) {
+			return fmt.Errorf("%w: character not allowed: %q", ErrInvalidAlias, character)
+		}
+	}
+
+	return nil
+}
+
+// isReservedAlias tells if the alias would shadow one of the server routes,
+// that is if it equals the first path segment of a route below the expander
+func (c *URLShortener) isReservedAlias(alias string) bool {
+	routes := []string{c.shortenRoute, c.statisticsRoute, c.apiRoute, c.openAPIRoute, c.bulkRoute, c.metricsRoute}
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route, c.expanderRoute) {
+			continue
+		}
+
+		relative := strings.TrimPrefix(route, c.expanderRoute)
+		reserved := strings.SplitN(strings.Trim(relative, "/"), "/", 2)[0]
+
+		if strings.EqualFold(alias, reserved) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// aliasURL registers a caller chosen alias as the short URL of the link.
+// Registering again the same alias for the same URL and owner is not an
+// error, the link is renewed with the new expiry and the boolean is set.
+func (c *URLShortener) aliasURL(alias string, link Link) (bool, error) {
+	if err := validateAlias(alias); err != nil {
+		return false, err
+	}
+
+	if c.isReservedAlias(alias) {
+		return false, fmt.Errorf("%w: %s", ErrReservedAlias, alias)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	free, taken, err := c.isFree(alias)
+
+	if err != nil {
+		return false, err
+	}
+
+	if !free && (taken.URL != link.URL || taken.Owner != link.Owner) {
+		return false, fmt.Errorf("%w: %s", ErrAliasTaken, alias)
+	}
+
+	return !free, c.putLink(alias, c.stamp(link))
+}
@@ -0,0 +1,126 @@
+package shorten
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAliasURL(t *testing.T) {
+	sut := NewURLShortener()
+
+	tests := []struct {
+		longURL string
+		alias   string
+		wantErr error
+	}{
+		{"https://wiki.example", "team-wiki", nil},
+		{"https://wiki.example", "team-wiki", nil},
+		{"https://other.example", "team-wiki", ErrAliasTaken},
+		{"https://wiki.example", "Team_Wiki2", nil},
+		{"https://wiki.example", "ab", ErrInvalidAlias},
+		{"https://wiki.example", strings.Repeat("a", 65), ErrInvalidAlias},
+		{"https://wiki.example", "team/wiki", ErrInvalidAlias},
+		{"https://wiki.example", "team wiki", ErrInvalidAlias},
+		{"https://wiki.example", "shorten", ErrReservedAlias},
+		{"https://wiki.example", "Statistics", ErrReservedAlias},
+	}
+
+	for _, test := range tests {
+		_, err := sut.aliasURL(test.alias, Link{URL: test.longURL})
+
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("Incorrect error for alias %s, got: %v, want: %v.", test.alias, err, test.wantErr)
+		}
+	}
+
+	longURL, err := sut.GetURL("team-wiki")
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL != "https://wiki.example" {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", longURL, "https://wiki.example")
+	}
+}
+
+func TestIsReservedAlias(t *testing.T) {
+	tests := []struct {
+		options   []Option
+		alias     string
+		wantValue bool
+	}{
+		{nil, "shorten", true},
+		{nil, "api", true},
+		{nil, "s", false},
+		{[]Option{WithRoutePrefix("/s/")}, "shorten", true},
+		{[]Option{WithRoutePrefix("/s/")}, "s", false},
+		{[]Option{WithExpanderRoute("/go/")}, "shorten", false},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener(test.options...)
+
+		if got := sut.isReservedAlias(test.alias); got != test.wantValue {
+			t.Errorf("Incorrect reserved value for alias %s, got: %v, want: %v.", test.alias, got, test.wantValue)
+		}
+	}
+}
+
+func TestShortenHandlerAlias(t *testing.T) {
+	sut := NewURLShortener()
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"url=https://wiki.example&alias=team-wiki", http.StatusOK, "<a href=\"http://localhost:9090/team-wiki\">team-wiki -> https://wiki.example</a>"},
+		{"url=https://wiki.example&alias=team-wiki", http.StatusOK, "<a href=\"http://localhost:9090/team-wiki\">team-wiki -> https://wiki.example</a>"},
+		{"url=https://other.example&alias=team-wiki", http.StatusConflict, ""},
+		{"url=https://other.example&alias=statistics", http.StatusBadRequest, ""},
+		{"url=https://other.example&alias=%3Cx%3E", http.StatusBadRequest, ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/shorten?"+test.query, nil)
+		request.Host = "localhost:9090"
+		responseRecorder := httptest.NewRecorder()
+
+		sut.shortenHandler(responseRecorder, request)
+
+		response := responseRecorder.Result()
+
+		if response.StatusCode != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.query, response.StatusCode, test.wantStatus)
+		}
+
+		if test.wantBody != "" && responseRecorder.Body.String() != test.wantBody {
+			t.Errorf("Incorrect body, got: %s, want: %s.", responseRecorder.Body.String(), test.wantBody)
+		}
+	}
+}
+
+func TestAliasPersistence(t *testing.T) {
+	sut := NewURLShortener()
+
+	if _, err := sut.aliasURL("team-wiki", Link{URL: "https://wiki.example"}); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	var builder strings.Builder
+	if err := sut.PersistTo(&builder); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	restored := NewURLShortener()
+	if err := restored.UnpersistFrom(strings.NewReader(builder.String())); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL, _ := restored.GetURL("team-wiki"); longURL != "https://wiki.example" {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", longURL, "https://wiki.example")
+	}
+}
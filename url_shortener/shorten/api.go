@@ -0,0 +1,383 @@
+package shorten
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pagination bounds of the links listing
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+
+	// maxAPIBodySize bounds the JSON bodies accepted by the API
+	maxAPIBodySize = 64 * 1024
+
+	// maxBulkBodySize bounds the bodies of bulk imports
+	maxBulkBodySize = 64 * 1024 * 1024
+)
+
+// bulkFormats the bulk formats by media type of the body
+var bulkFormats = map[string]BulkFormat{
+	"text/csv":             FormatCSV,
+	"application/x-ndjson": FormatJSONLines,
+	"application/jsonl":    FormatJSONLines,
+	"application/json":     FormatPersistence,
+}
+
+type linkJSON struct {
+	Code         string     `json:"code"`
+	ShortURL     string     `json:"short_url"`
+	LongURL      string     `json:"long_url"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+}
+
+type linksPageJSON struct {
+	Links  []linkJSON `json:"links"`
+	Total  int        `json:"total"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+type createLinkJSON struct {
+	URL          string `json:"url"`
+	Alias        string `json:"alias,omitempty"`
+	TTL          string `json:"ttl,omitempty"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	RedirectCode int    `json:"redirect_code,omitempty"`
+	Interstitial bool   `json:"interstitial,omitempty"`
+}
+
+type revokedJSON struct {
+	Deleted int `json:"deleted"`
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorJSON{message})
+}
+
+func (c *URLShortener) newLinkJSON(r *http.Request, shortURL string, link Link) linkJSON {
+	return linkJSON{
+		Code:         shortURL,
+		ShortURL:     c.shortLink(r, shortURL),
+		LongURL:      link.URL,
+		CreatedAt:    optionalTime(link.CreatedAt),
+		ExpiresAt:    optionalTime(link.ExpiresAt),
+		Owner:        link.Owner,
+		RedirectCode: link.RedirectCode,
+		Interstitial: link.Interstitial,
+	}
+}
+
+// isOwnedBy tells if the link is visible to the API key of r, every link is
+// when API keys are not required
+func (c *URLShortener) isOwnedBy(r *http.Request, link Link) bool {
+	return c.apiKeys == nil || link.Owner == ownerFrom(r.Context())
+}
+
+// apiHandler serves the links resource: the collection at the API route and
+// each link at the API route followed by its code
+func (c *URLShortener) apiHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, c.apiRoute)
+	code := strings.TrimPrefix(path, "/")
+
+	switch {
+	case code == "" && r.Method == http.MethodGet:
+		c.listLinks(w, r)
+	case code == "" && r.Method == http.MethodPost:
+		c.createLink(w, r)
+	case code == "" && r.Method == http.MethodDelete && c.apiKeys != nil:
+		c.revokeLinks(w, r)
+	case code == "" && c.apiKeys != nil:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case code == "":
+		w.Header().Set("Allow", "GET, POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case strings.Contains(code, "/"):
+		writeJSONError(w, http.StatusNotFound, "not found")
+	case r.Method == http.MethodGet:
+		c.getLink(w, r, code)
+	case r.Method == http.MethodDelete:
+		c.deleteLink(w, r, code)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (c *URLShortener) createLink(w http.ResponseWriter, r *http.Request) {
+	if !c.allowRequest(w, r, c.createLimiter) {
+		writeJSONError(w, http.StatusTooManyRequests, rateLimitedMessage)
+		return
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBodySize))
+
+	var request createLinkJSON
+
+	if err := decoder.Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %s", err))
+		return
+	}
+
+	expiresAt, err := parseExpiry(c.now(), request.TTL, request.ExpiresAt)
+
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validateRedirectCode(request.RedirectCode); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	requested := Link{URL: request.URL, ExpiresAt: expiresAt, RedirectCode: request.RedirectCode, Interstitial: request.Interstitial}
+
+	shortURL, existing, err := c.createShortURL(r, request.Alias, requested)
+
+	if err != nil {
+		writeJSONError(w, shortenErrorStatus(err), err.Error())
+		return
+	}
+
+	link, err := c.GetLink(shortURL)
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", c.apiRoute, shortURL))
+
+	status := http.StatusCreated
+	if existing {
+		status = http.StatusOK
+	}
+
+	writeJSON(w, status, c.newLinkJSON(r, shortURL, link))
+}
+
+func (c *URLShortener) getLink(w http.ResponseWriter, r *http.Request, shortURL string) {
+	// the owner is checked first, so links of other keys are never told
+	// apart from missing ones
+	link, err := c.store.Get(shortURL)
+
+	if errors.Is(err, ErrNotFound) || (err == nil && !c.isOwnedBy(r, link)) {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s: %s", ErrNotFound, shortURL))
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if link.isExpired(c.now()) {
+		writeJSONError(w, http.StatusGone, fmt.Sprintf("%s: %s", ErrExpired, shortURL))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, c.newLinkJSON(r, shortURL, link))
+}
+
+func (c *URLShortener) deleteLink(w http.ResponseWriter, r *http.Request, shortURL string) {
+	err := c.deleteURLIf(shortURL, func(link Link) bool {
+		return c.isOwnedBy(r, link)
+	})
+
+	if err == ErrNotFound || err == errKept {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("%s: %s", ErrNotFound, shortURL))
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeLinks deletes every link of the API key of r
+func (c *URLShortener) revokeLinks(w http.ResponseWriter, r *http.Request) {
+	deleted, err := c.RevokeLinks(ownerFrom(r.Context()))
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, revokedJSON{deleted})
+}
+
+// parsePageParameter parses a non negative pagination query parameter
+func parsePageParameter(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %s", name, value)
+	}
+
+	return n, nil
+}
+
+// listLinks writes a page of links sorted by code
+func (c *URLShortener) listLinks(w http.ResponseWriter, r *http.Request) {
+	offset, err := parsePageParameter(r, "offset", 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := parsePageParameter(r, "limit", defaultPageLimit)
+	if err != nil || limit == 0 || limit > maxPageLimit {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+		return
+	}
+
+	links := make([]linkJSON, 0)
+
+	now := c.now()
+
+	err = c.store.Iterate(func(shortURL string, link Link) bool {
+		if !link.isExpired(now) && c.isOwnedBy(r, link) {
+			links = append(links, c.newLinkJSON(r, shortURL, link))
+		}
+		return true
+	})
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Code < links[j].Code
+	})
+
+	page := linksPageJSON{Total: len(links), Offset: offset, Limit: limit}
+
+	if offset > len(links) {
+		offset = len(links)
+	}
+
+	end := offset + limit
+	if end > len(links) {
+		end = len(links)
+	}
+
+	page.Links = links[offset:end]
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// bulkFormat returns the format of a bulk import from the format query
+// parameter or else from the media type of the body
+func bulkFormat(r *http.Request) (BulkFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		return ParseBulkFormat(name)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if format, ok := bulkFormats[mediaType]; ok {
+		return format, nil
+	}
+
+	return "", fmt.Errorf("%w: %q, set the format parameter to csv, jsonl or json", ErrUnknownFormat, mediaType)
+}
+
+// bulkHandler imports the links of the body into the running server, they
+// belong to the API key of the request when API keys are required
+func (c *URLShortener) bulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if !c.allowRequest(w, r, c.createLimiter) {
+		writeJSONError(w, http.StatusTooManyRequests, rateLimitedMessage)
+		return
+	}
+
+	format, err := bulkFormat(r)
+
+	if err != nil {
+		writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	rows, err := readBulkRows(http.MaxBytesReader(w, r.Body, maxBulkBodySize), format)
+
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s: %s", ErrMalformedBulk, err))
+		return
+	}
+
+	// every row is charged as a link created, the first one before reading
+	// the body. Imports larger than the burst could never be let through.
+	if c.createLimiter != nil && len(rows) > c.createLimiter.limit.Burst {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d rows per import", c.createLimiter.limit.Burst))
+		return
+	}
+
+	if len(rows) > 1 && !c.allowRequestN(w, r, c.createLimiter, len(rows)-1) {
+		writeJSONError(w, http.StatusTooManyRequests, rateLimitedMessage)
+		return
+	}
+
+	var owner *string
+	if c.apiKeys != nil {
+		name := ownerFrom(r.Context())
+		owner = &name
+	}
+
+	report, err := c.importRows(rows, owner)
+
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// openAPIHandler serves the OpenAPI document of the links API
+func (c *URLShortener) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	replacer := strings.NewReplacer("{{links}}", c.apiRoute, "{{bulk}}", c.bulkRoute)
+
+	fmt.Fprint(w, replacer.Replace(openAPIDocument))
+}
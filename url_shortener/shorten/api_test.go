@@ -0,0 +1,173 @@
+package shorten
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveAPI(sut *URLShortener, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Host = "localhost:9090"
+	responseRecorder := httptest.NewRecorder()
+
+	sut.apiHandler(responseRecorder, request)
+
+	return responseRecorder
+}
+
+func TestAPICreateLink(t *testing.T) {
+	sut := NewURLShortener()
+
+	tests := []struct {
+		body         string
+		wantStatus   int
+		wantShortURL string
+	}{
+		{`{"url":"https://github.com/develersrl/powersoft-hmi"}`, http.StatusCreated, "http://localhost:9090/4611ce1"},
+		{`{"url":"https://github.com/develersrl/powersoft-hmi"}`, http.StatusOK, "http://localhost:9090/4611ce1"},
+		{`{"url":"https://wiki.example","alias":"team-wiki"}`, http.StatusCreated, "http://localhost:9090/team-wiki"},
+		{`{"url":"https://wiki.example","alias":"team-wiki"}`, http.StatusOK, "http://localhost:9090/team-wiki"},
+		{`{"url":"https://other.example","alias":"team-wiki"}`, http.StatusConflict, ""},
+		{`{"url":"https://other.example","alias":"api"}`, http.StatusBadRequest, ""},
+		{`{"alias":"no-url"}`, http.StatusBadRequest, ""},
+		{`{"url":`, http.StatusBadRequest, ""},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAPI(sut, "POST", "/api/v1/links", test.body)
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.body, responseRecorder.Code, test.wantStatus)
+		}
+
+		if responseRecorder.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+			t.Errorf("Unexpected content type, got: %s.", responseRecorder.Header().Get("Content-Type"))
+		}
+
+		if test.wantShortURL == "" {
+			continue
+		}
+
+		var link linkJSON
+		if err := json.Unmarshal(responseRecorder.Body.Bytes(), &link); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if link.ShortURL != test.wantShortURL {
+			t.Errorf("Incorrect short URL, got: %s, want: %s.", link.ShortURL, test.wantShortURL)
+		}
+
+		if location := responseRecorder.Header().Get("Location"); location != "/api/v1/links/"+link.Code {
+			t.Errorf("Incorrect location, got: %s, want: %s.", location, "/api/v1/links/"+link.Code)
+		}
+	}
+}
+
+func TestAPIGetAndDeleteLink(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	tests := []struct {
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{"GET", "/api/v1/links/4611ce1", http.StatusOK},
+		{"GET", "/api/v1/links/1234567", http.StatusNotFound},
+		{"PUT", "/api/v1/links/4611ce1", http.StatusMethodNotAllowed},
+		{"GET", "/api/v1/links/4611ce1/extra", http.StatusNotFound},
+		{"DELETE", "/api/v1/links/4611ce1", http.StatusNoContent},
+		{"DELETE", "/api/v1/links/4611ce1", http.StatusNotFound},
+		{"GET", "/api/v1/links/4611ce1", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAPI(sut, test.method, test.target, "")
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s %s, got: %v, want: %v.", test.method, test.target, responseRecorder.Code, test.wantStatus)
+		}
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 0 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 0)
+	}
+}
+
+func TestAPIListLinks(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://c.example", "ccc")
+	sut.addURL("https://a.example", "aaa")
+	sut.addURL("https://b.example", "bbb")
+
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantCodes  []string
+	}{
+		{"/api/v1/links", http.StatusOK, []string{"aaa", "bbb", "ccc"}},
+		{"/api/v1/links?limit=2", http.StatusOK, []string{"aaa", "bbb"}},
+		{"/api/v1/links?offset=2&limit=2", http.StatusOK, []string{"ccc"}},
+		{"/api/v1/links?offset=5", http.StatusOK, []string{}},
+		{"/api/v1/links?limit=0", http.StatusBadRequest, nil},
+		{"/api/v1/links?offset=-1", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		responseRecorder := serveAPI(sut, "GET", test.target, "")
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.target, responseRecorder.Code, test.wantStatus)
+		}
+
+		if test.wantCodes == nil {
+			continue
+		}
+
+		var page linksPageJSON
+		if err := json.Unmarshal(responseRecorder.Body.Bytes(), &page); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if page.Total != 3 {
+			t.Errorf("Incorrect total, got: %v, want: %v.", page.Total, 3)
+		}
+
+		if len(page.Links) != len(test.wantCodes) {
+			t.Fatalf("Incorrect number of links for %s, got: %v, want: %v.", test.target, len(page.Links), len(test.wantCodes))
+		}
+
+		for i, code := range test.wantCodes {
+			if page.Links[i].Code != code {
+				t.Errorf("Incorrect code at %v, got: %s, want: %s.", i, page.Links[i].Code, code)
+			}
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	sut := NewURLShortener()
+
+	request := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.openAPIHandler(responseRecorder, request)
+
+	var document struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	for _, path := range []string{"/api/v1/links", "/api/v1/links/{code}"} {
+		if _, ok := document.Paths[path]; !ok {
+			t.Errorf("Missing path in OpenAPI document: %s.", path)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package shorten
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// bearerPrefix the prefix of the Authorization header carrying an API key
+const bearerPrefix = "Bearer "
+
+type ownerKey struct{}
+
+// APIKeys the API keys allowed to use the protected routes, by name
+type APIKeys struct {
+	names map[[sha256.Size]byte]string
+}
+
+// LoadAPIKeys reads API keys, one per line as a name followed by the key.
+// Empty lines and lines starting with # are skipped. The name identifies the
+// key as owner of the links it creates.
+func LoadAPIKeys(r io.Reader) (*APIKeys, error) {
+	keys := APIKeys{}
+
+	keys.names = make(map[[sha256.Size]byte]string)
+
+	scanner := bufio.NewScanner(r)
+	names := make(map[string]bool)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want a name and a key", lineNumber)
+		}
+
+		name, key := fields[0], fields[1]
+		digest := sha256.Sum256([]byte(key))
+
+		if _, ok := keys.names[digest]; ok || names[name] {
+			return nil, fmt.Errorf("line %d: duplicate name or key: %s", lineNumber, name)
+		}
+
+		keys.names[digest] = name
+		names[name] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(keys.names) == 0 {
+		return nil, fmt.Errorf("no API keys")
+	}
+
+	return &keys, nil
+}
+
+// owner returns the name of a key. Keys are looked up by digest, so the
+// lookup time does not depend on how much of a key matches.
+func (k *APIKeys) owner(key string) (string, bool) {
+	name, ok := k.names[sha256.Sum256([]byte(key))]
+
+	return name, ok
+}
+
+// ownerFrom returns the name of the API key that authenticated the request of
+// ctx, empty when API keys are not required
+func ownerFrom(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+
+	return owner
+}
+
+// authenticate requires requests to carry a known API key as a bearer token
+// when API keys are configured, errors are written with writeError
+func (c *URLShortener) authenticate(next http.HandlerFunc, writeError func(http.ResponseWriter, int, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.apiKeys == nil {
+			next(w, r)
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+
+		if !strings.HasPrefix(authorization, bearerPrefix) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="shortener"`)
+			writeError(w, http.StatusUnauthorized, "missing API key")
+			return
+		}
+
+		owner, ok := c.apiKeys.owner(strings.TrimSpace(authorization[len(bearerPrefix):]))
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="shortener", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ownerKey{}, owner)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// writeTextError writes an error as plain text
+func writeTextError(w http.ResponseWriter, status int, message string) {
+	http.Error(w, message, status)
+}
+
+// RevokeLinks removes the links created with the API key named owner, it
+// returns the number of links removed
+func (c *URLShortener) RevokeLinks(owner string) (int, error) {
+	owned := make([]string, 0)
+
+	err := c.store.Iterate(func(shortURL string, link Link) bool {
+		if link.Owner == owner {
+			owned = append(owned, shortURL)
+		}
+		return true
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, shortURL := range owned {
+		err := c.deleteURLIf(shortURL, func(link Link) bool {
+			return link.Owner == owner
+		})
+
+		if err == errKept || err == ErrNotFound {
+			continue // taken over or removed in the meanwhile
+		}
+
+		if err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
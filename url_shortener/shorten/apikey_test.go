@@ -0,0 +1,204 @@
+package shorten
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAPIKeys = `# team keys
+alice 4l1c3-s3cr3t
+
+bob   b0b-s3cr3t
+`
+
+func newAuthenticatedRequest(method, target, key string, body string) *http.Request {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	return request
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	keys, err := LoadAPIKeys(strings.NewReader(testAPIKeys))
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	tests := []struct {
+		key       string
+		wantOwner string
+		wantOk    bool
+	}{
+		{"4l1c3-s3cr3t", "alice", true},
+		{"b0b-s3cr3t", "bob", true},
+		{"alice", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		owner, ok := keys.owner(test.key)
+
+		if owner != test.wantOwner || ok != test.wantOk {
+			t.Errorf("Incorrect owner for key %s, got: %s %v, want: %s %v.", test.key, owner, ok, test.wantOwner, test.wantOk)
+		}
+	}
+
+	invalid := []string{"", "# only comments\n", "alice\n", "alice k1\nbob k1\n", "alice k1\nalice k2\n"}
+
+	for _, data := range invalid {
+		if _, err := LoadAPIKeys(strings.NewReader(data)); err == nil {
+			t.Errorf("Expected error loading API keys: %q.", data)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	keys, _ := LoadAPIKeys(strings.NewReader(testAPIKeys))
+
+	sut := NewURLShortener(WithAPIKeys(keys))
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	tests := []struct {
+		method         string
+		path           string
+		key            string
+		wantStatusCode int
+	}{
+		{"GET", "/shorten?url=https://wttr.in/Rome", "", http.StatusUnauthorized},
+		{"GET", "/shorten?url=https://wttr.in/Rome", "wrong", http.StatusUnauthorized},
+		{"GET", "/shorten?url=https://wttr.in/Rome", "4l1c3-s3cr3t", http.StatusOK},
+		{"GET", "/statistics", "", http.StatusUnauthorized},
+		{"GET", "/statistics", "b0b-s3cr3t", http.StatusOK},
+		{"GET", "/api/v1/links", "", http.StatusUnauthorized},
+		{"GET", "/metrics", "", http.StatusUnauthorized},
+		{"GET", "/api/v1/openapi.json", "", http.StatusOK},
+		{"GET", "/f495791", "", http.StatusSeeOther},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, newAuthenticatedRequest(test.method, test.path, test.key, ""))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s with key %q, got: %v, want: %v.", test.path, test.key, responseRecorder.Code, test.wantStatusCode)
+		}
+
+		if test.wantStatusCode == http.StatusUnauthorized && responseRecorder.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Missing WWW-Authenticate header for %s.", test.path)
+		}
+	}
+}
+
+func TestLinksPerKey(t *testing.T) {
+	keys, _ := LoadAPIKeys(strings.NewReader(testAPIKeys))
+
+	sut := NewURLShortener(WithAPIKeys(keys))
+
+	creations := []struct {
+		key  string
+		body string
+	}{
+		{"4l1c3-s3cr3t", `{"url":"https://wttr.in/Rome"}`},
+		{"4l1c3-s3cr3t", `{"url":"https://wttr.in/Florence"}`},
+		{"b0b-s3cr3t", `{"url":"https://wttr.in/Rome"}`},
+	}
+
+	codes := make([]string, 0)
+
+	for _, creation := range creations {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("POST", "/api/v1/links", creation.key, creation.body))
+
+		var link linkJSON
+		json.Unmarshal(responseRecorder.Body.Bytes(), &link)
+
+		if responseRecorder.Code != http.StatusCreated {
+			t.Fatalf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusCreated)
+		}
+
+		codes = append(codes, link.Code)
+	}
+
+	if codes[0] == codes[2] {
+		t.Errorf("Unexpected shared short URL %s between API keys.", codes[0])
+	}
+
+	if link, _ := sut.GetLink(codes[2]); link.Owner != "bob" {
+		t.Errorf("Incorrect owner, got: %s, want: %s.", link.Owner, "bob")
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("GET", "/api/v1/links", "b0b-s3cr3t", ""))
+
+	var page linksPageJSON
+	json.Unmarshal(responseRecorder.Body.Bytes(), &page)
+
+	if page.Total != 1 || page.Links[0].Code != codes[2] {
+		t.Errorf("Incorrect links of bob, got: %v.", page.Links)
+	}
+
+	responseRecorder = httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("DELETE", "/api/v1/links/"+codes[0], "b0b-s3cr3t", ""))
+
+	if responseRecorder.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status code deleting a link of another key, got: %v, want: %v.", responseRecorder.Code, http.StatusNotFound)
+	}
+
+	responseRecorder = httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("DELETE", "/api/v1/links", "4l1c3-s3cr3t", ""))
+
+	var revoked revokedJSON
+	json.Unmarshal(responseRecorder.Body.Bytes(), &revoked)
+
+	if responseRecorder.Code != http.StatusOK || revoked.Deleted != 2 {
+		t.Errorf("Incorrect revoked links, got: %v %v, want: %v %v.", responseRecorder.Code, revoked.Deleted, http.StatusOK, 2)
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 1 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 1)
+	}
+}
+
+func TestExpiredLinksOfOtherKeys(t *testing.T) {
+	keys, _ := LoadAPIKeys(strings.NewReader(testAPIKeys))
+
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	sut := NewURLShortener(WithAPIKeys(keys))
+	sut.now = func() time.Time {
+		return now
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("POST", "/api/v1/links", "4l1c3-s3cr3t", `{"url":"https://wttr.in/Rome","ttl":"1h"}`))
+
+	var link linkJSON
+	json.Unmarshal(responseRecorder.Body.Bytes(), &link)
+
+	now = now.Add(2 * time.Hour)
+
+	tests := []struct {
+		key        string
+		wantStatus int
+	}{
+		{"4l1c3-s3cr3t", http.StatusGone},
+		{"b0b-s3cr3t", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+		sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("GET", "/api/v1/links/"+link.Code, test.key, ""))
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.key, responseRecorder.Code, test.wantStatus)
+		}
+	}
+}
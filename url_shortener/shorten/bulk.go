@@ -0,0 +1,528 @@
+package shorten
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BulkFormat a format of bulk imports and exports
+type BulkFormat string
+
+// Bulk formats: CSV with a header row, JSON Lines with a link object per
+// line and the persistence JSON object of links by code
+const (
+	FormatCSV         BulkFormat = "csv"
+	FormatJSONLines   BulkFormat = "jsonl"
+	FormatPersistence BulkFormat = "json"
+)
+
+// importBatchSize bounds the rows imported while holding the mutex, so large
+// imports let other writers through between batches
+const importBatchSize = 1000
+
+// Errors of bulk imports, ErrInvalidRow matches the errors of the rows
+// skipped as not valid
+var (
+	ErrUnknownFormat = errors.New("unknown bulk format")
+	ErrMalformedBulk = errors.New("malformed bulk import")
+	ErrInvalidRow    = errors.New("invalid row")
+)
+
+// rowError an error making a row not valid, it keeps the message of the
+// error wrapped and matches ErrInvalidRow too
+type rowError struct {
+	err error
+}
+
+func (e rowError) Error() string {
+	return e.err.Error()
+}
+
+func (e rowError) Unwrap() error {
+	return e.err
+}
+
+func (e rowError) Is(target error) bool {
+	return target == ErrInvalidRow
+}
+
+// maxBulkLineSize bounds the JSON Lines records
+const maxBulkLineSize = 1024 * 1024
+
+// csvColumns the columns of the CSV format, only url is required on import
+var csvColumns = []string{"code", "url", "created_at", "expires_at", "owner", "redirect_code", "interstitial"}
+
+// ParseBulkFormat parses a bulk format from its name: csv, jsonl or json
+func ParseBulkFormat(name string) (BulkFormat, error) {
+	switch format := BulkFormat(strings.ToLower(name)); format {
+	case FormatCSV, FormatJSONLines, FormatPersistence:
+		return format, nil
+	case "ndjson":
+		return FormatJSONLines, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// BulkIssue a row of a bulk import that was not imported
+type BulkIssue struct {
+	Row   int    `json:"row"`
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportReport the outcome of a bulk import: the links imported, the ones
+// already there, the rows with codes taken by other links and the rows not
+// valid
+type ImportReport struct {
+	Imported  int         `json:"imported"`
+	Unchanged int         `json:"unchanged"`
+	Conflicts []BulkIssue `json:"conflicts"`
+	Invalid   []BulkIssue `json:"invalid"`
+}
+
+// bulkRow a decoded row of a bulk import, err is set for rows that could not
+// be decoded
+type bulkRow struct {
+	row  int
+	code string
+	link Link
+	err  error
+}
+
+// bulkLinkJSON a JSON Lines record, a persisted link with its code
+type bulkLinkJSON struct {
+	Code string `json:"code,omitempty"`
+	linkFileJSON
+}
+
+// readBulkRows decodes the rows of a bulk import, rows not valid are kept
+// with their error while malformed inputs are an error
+func readBulkRows(r io.Reader, format BulkFormat) ([]bulkRow, error) {
+	switch format {
+	case FormatCSV:
+		return readCSVRows(r)
+	case FormatJSONLines:
+		return readJSONLinesRows(r)
+	case FormatPersistence:
+		return readPersistenceRows(r)
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// readCSVRows decodes CSV rows by the column names of the header, unknown
+// columns are ignored
+func readCSVRows(r io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing CSV header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("missing url column in CSV header")
+	}
+
+	rows := make([]bulkRow, 0)
+
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			rows = append(rows, bulkRow{row: row, err: err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[i])
+		}
+
+		link := linkFileJSON{URL: field("url"), Owner: field("owner")}
+		err = parseCSVFields(&link, field("created_at"), field("expires_at"), field("redirect_code"), field("interstitial"))
+
+		rows = append(rows, bulkRow{row: row, code: field("code"), link: link.link(), err: err})
+	}
+
+	return rows, nil
+}
+
+// parseCSVFields parses the CSV fields that are not strings, empty ones are
+// left as zero values
+func parseCSVFields(link *linkFileJSON, createdAt, expiresAt, redirectCode, interstitial string) error {
+	var err error
+
+	if createdAt != "" {
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return fmt.Errorf("created_at must be an RFC 3339 time: %s", createdAt)
+		}
+		link.CreatedAt = &t
+	}
+
+	if expiresAt != "" {
+		var t time.Time
+		if t, err = time.Parse(time.RFC3339, expiresAt); err != nil {
+			return fmt.Errorf("%w: expires_at must be an RFC 3339 time: %s", ErrInvalidExpiry, expiresAt)
+		}
+		link.ExpiresAt = &t
+	}
+
+	if link.RedirectCode, err = parseRedirectCode(redirectCode); err != nil {
+		return err
+	}
+
+	link.Interstitial, err = parseInterstitial(interstitial)
+
+	return err
+}
+
+// readJSONLinesRows decodes a link object per line, blank lines are skipped
+func readJSONLinesRows(r io.Reader) ([]bulkRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBulkLineSize)
+
+	rows := make([]bulkRow, 0)
+
+	for row := 1; scanner.Scan(); row++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record bulkLinkJSON
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			rows = append(rows, bulkRow{row: row, err: fmt.Errorf("invalid JSON: %s", err)})
+			continue
+		}
+
+		rows = append(rows, bulkRow{row: row, code: record.Code, link: record.link()})
+	}
+
+	return rows, scanner.Err()
+}
+
+// readPersistenceRows decodes the persistence JSON object keeping the order
+// of its links, numbered from 1
+func readPersistenceRows(r io.Reader) ([]bulkRow, error) {
+	decoder := json.NewDecoder(r)
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("persistence JSON must be an object of links by code")
+	}
+
+	rows := make([]bulkRow, 0)
+
+	for row := 1; decoder.More(); row++ {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		code := token.(string)
+
+		var link Link
+		if err := json.Unmarshal(value, &link); err != nil {
+			rows = append(rows, bulkRow{row: row, code: code, err: fmt.Errorf("invalid link: %s", err)})
+			continue
+		}
+
+		rows = append(rows, bulkRow{row: row, code: code, link: link})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// validateCode checks a short URL imported as is: it must be URL safe and
+// must not shadow the server routes
+func (c *URLShortener) validateCode(code string) error {
+	if len(code) > maxAliasLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidAlias, maxAliasLength)
+	}
+
+	for _, character := range code {
+		if !strings.ContainsRune(urlSafeCharacters, character) {
+			return fmt.Errorf("%w: character not allowed: %q", ErrInvalidAlias, character)
+		}
+	}
+
+	if _, _, ok := splitQRCodePath(code); ok || c.isReservedAlias(code) {
+		return fmt.Errorf("%w: %s", ErrReservedAlias, code)
+	}
+
+	return nil
+}
+
+// Import imports the links read from r in the format passed in, keeping
+// their codes and owners. Links without code get a generated one. Rows not
+// valid and rows whose code is taken by another link are skipped and
+// reported, malformed inputs are an error and nothing is imported. A storage
+// failure stops the import and is returned with the rows imported so far.
+func (c *URLShortener) Import(r io.Reader, format BulkFormat) (ImportReport, error) {
+	return c.importLinks(r, format, nil)
+}
+
+// importLinks imports the links read from r, owner replaces the owner of
+// every link when not nil. The rows are decoded before taking the mutex, so
+// slow readers never hold back other writers.
+func (c *URLShortener) importLinks(r io.Reader, format BulkFormat, owner *string) (ImportReport, error) {
+	rows, err := readBulkRows(r, format)
+	if err != nil {
+		report := ImportReport{Conflicts: make([]BulkIssue, 0), Invalid: make([]BulkIssue, 0)}
+		return report, fmt.Errorf("%w: %s", ErrMalformedBulk, err)
+	}
+
+	return c.importRows(rows, owner)
+}
+
+// importRows imports the rows decoded in batches of importBatchSize
+func (c *URLShortener) importRows(rows []bulkRow, owner *string) (ImportReport, error) {
+	report := ImportReport{Conflicts: make([]BulkIssue, 0), Invalid: make([]BulkIssue, 0)}
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		if err := c.importBatch(rows[start:end], owner, &report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// importBatch imports a batch of rows holding the mutex and adds them to the
+// report, the write-ahead log is synced once per batch, on storage failures
+// too so the rows imported before are durable
+func (c *URLShortener) importBatch(rows []bulkRow, owner *string, report *ImportReport) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	importedBefore := report.Imported
+	var failure error
+
+	for _, row := range rows {
+		if owner != nil {
+			row.link.Owner = *owner
+		}
+
+		imported, err := c.importRow(row)
+
+		switch {
+		case errors.Is(err, ErrAliasTaken):
+			report.Conflicts = append(report.Conflicts, BulkIssue{row.row, row.code, err.Error()})
+		case errors.Is(err, ErrInvalidRow):
+			report.Invalid = append(report.Invalid, BulkIssue{row.row, row.code, err.Error()})
+		case err != nil:
+			failure = fmt.Errorf("row %d: %w", row.row, err)
+		case imported:
+			report.Imported++
+		default:
+			report.Unchanged++
+		}
+
+		if failure != nil {
+			break
+		}
+	}
+
+	if c.wal != nil && report.Imported > importedBefore {
+		if err := c.wal.sync(); err != nil && failure == nil {
+			failure = err
+		}
+	}
+
+	return failure
+}
+
+// importRow stores the link of a row, it returns false for links already
+// stored with the same code. The errors of rows not valid match
+// ErrInvalidRow, the other ones are storage failures. The caller holds the
+// mutex.
+func (c *URLShortener) importRow(row bulkRow) (bool, error) {
+	if row.err != nil {
+		return false, rowError{row.err}
+	}
+
+	link := row.link
+
+	longURL, err := c.normalizeURL(nil, link.URL)
+	if err != nil {
+		return false, rowError{err}
+	}
+	link.URL = longURL
+
+	if err := validateRedirectCode(link.RedirectCode); err != nil {
+		return false, rowError{err}
+	}
+
+	if link.isExpired(c.now()) {
+		return false, rowError{fmt.Errorf("%w: expired at %s", ErrInvalidExpiry, link.ExpiresAt.Format(time.RFC3339))}
+	}
+
+	if link.CreatedAt.IsZero() {
+		link = c.stamp(link)
+	}
+
+	code := row.code
+
+	if code == "" {
+		generated, existing, err := c.generateCode(link)
+		if err != nil || existing {
+			return false, err
+		}
+
+		return true, c.writeLink(generated, link, false)
+	}
+
+	if err := c.validateCode(code); err != nil {
+		return false, rowError{err}
+	}
+
+	free, taken, err := c.isFree(code)
+	if err != nil {
+		return false, err
+	}
+
+	if !free && (taken.URL != link.URL || taken.Owner != link.Owner) {
+		return false, fmt.Errorf("%w: %s", ErrAliasTaken, code)
+	}
+
+	if !free {
+		return false, nil
+	}
+
+	return true, c.writeLink(code, link, false)
+}
+
+// exportedLinks the links not expired sorted by code
+func (c *URLShortener) exportedLinks() ([]string, map[string]Link, error) {
+	now := c.now()
+	codes := make([]string, 0)
+	links := make(map[string]Link)
+
+	err := c.store.Iterate(func(shortURL string, link Link) bool {
+		if !link.isExpired(now) {
+			codes = append(codes, shortURL)
+			links[shortURL] = link
+		}
+		return true
+	})
+
+	sort.Strings(codes)
+
+	return codes, links, err
+}
+
+// Export writes the links not expired in the format passed in sorted by
+// code, it returns the number of links written
+func (c *URLShortener) Export(w io.Writer, format BulkFormat) (int, error) {
+	codes, links, err := c.exportedLinks()
+	if err != nil {
+		return 0, err
+	}
+
+	switch format {
+	case FormatCSV:
+		err = writeCSVLinks(w, codes, links)
+	case FormatJSONLines:
+		encoder := json.NewEncoder(w)
+		for _, code := range codes {
+			record := bulkLinkJSON{Code: code, linkFileJSON: links[code].fileJSON()}
+
+			if err = encoder.Encode(&record); err != nil {
+				break
+			}
+		}
+	case FormatPersistence:
+		err = json.NewEncoder(w).Encode(links)
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(codes), nil
+}
+
+func writeCSVLinks(w io.Writer, codes []string, links map[string]Link) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	}
+
+	for _, code := range codes {
+		link := links[code]
+
+		redirectCode := ""
+		if link.RedirectCode != 0 {
+			redirectCode = strconv.Itoa(link.RedirectCode)
+		}
+
+		interstitial := ""
+		if link.Interstitial {
+			interstitial = "true"
+		}
+
+		record := []string{code, link.URL, formatTime(link.CreatedAt), formatTime(link.ExpiresAt), link.Owner, redirectCode, interstitial}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
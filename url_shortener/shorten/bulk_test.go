@@ -0,0 +1,387 @@
+package shorten
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBulkCSV = `Code,URL,Expires_At,Redirect_Code,Interstitial,Clicks
+rome,https://wttr.in/Rome,,,,12
+,https://wttr.in/Florence,,301,,3
+rome,https://wttr.in/Rome,,,,0
+taken,https://wttr.in/Milan,,,,0
+bad,ftp://wttr.in/Turin,,,,0
+api,https://wttr.in/Genoa,,,,0
+old,https://wttr.in/Naples,2020-09-01T00:00:00Z,,,0
+flag,https://wttr.in/Bari,,,maybe,0
+`
+
+func newBulkTestShortener() *URLShortener {
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	sut.addURL("https://wttr.in/Venice", "taken")
+
+	return sut
+}
+
+func TestParseBulkFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantFormat BulkFormat
+		wantError  error
+	}{
+		{"csv", FormatCSV, nil},
+		{"JSONL", FormatJSONLines, nil},
+		{"ndjson", FormatJSONLines, nil},
+		{"json", FormatPersistence, nil},
+		{"xml", "", ErrUnknownFormat},
+	}
+
+	for _, test := range tests {
+		got, err := ParseBulkFormat(test.name)
+
+		if !errors.Is(err, test.wantError) {
+			t.Errorf("Incorrect error for %q, got: %v, want: %v.", test.name, err, test.wantError)
+		}
+
+		if got != test.wantFormat {
+			t.Errorf("Incorrect format for %q, got: %v, want: %v.", test.name, got, test.wantFormat)
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	sut := newBulkTestShortener()
+
+	report, err := sut.Import(strings.NewReader(testBulkCSV), FormatCSV)
+
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if report.Imported != 2 || report.Unchanged != 1 {
+		t.Errorf("Incorrect imported and unchanged, got: %v and %v, want: %v and %v.", report.Imported, report.Unchanged, 2, 1)
+	}
+
+	wantConflicts := []int{5}
+	if got := issueRows(report.Conflicts); !reflect.DeepEqual(got, wantConflicts) {
+		t.Errorf("Incorrect conflicts, got: %v, want: %v.", report.Conflicts, wantConflicts)
+	}
+
+	wantInvalid := []int{6, 7, 8, 9}
+	if got := issueRows(report.Invalid); !reflect.DeepEqual(got, wantInvalid) {
+		t.Errorf("Incorrect invalid rows, got: %v, want: %v.", report.Invalid, wantInvalid)
+	}
+
+	link, err := sut.GetLink("rome")
+	if err != nil || link.URL != "https://wttr.in/Rome" || !link.CreatedAt.Equal(sut.now()) {
+		t.Errorf("Incorrect imported link, got: %v, %v.", link, err)
+	}
+
+	florence, err := sut.GetLink(Shorten("https://wttr.in/Florence"))
+	if err != nil || florence.RedirectCode != http.StatusMovedPermanently {
+		t.Errorf("Incorrect generated link, got: %v, %v.", florence, err)
+	}
+
+	if taken, _ := sut.GetLink("taken"); taken.URL != "https://wttr.in/Venice" {
+		t.Errorf("Incorrect conflicting link, got: %v, want: %v.", taken.URL, "https://wttr.in/Venice")
+	}
+}
+
+func issueRows(issues []BulkIssue) []int {
+	rows := make([]int, 0, len(issues))
+
+	for _, issue := range issues {
+		rows = append(rows, issue.Row)
+	}
+
+	return rows
+}
+
+func TestImportMalformed(t *testing.T) {
+	tests := []struct {
+		format BulkFormat
+		data   string
+	}{
+		{FormatCSV, ""},
+		{FormatCSV, "code,long_url\nrome,https://wttr.in/Rome\n"},
+		{FormatPersistence, `["https://wttr.in/Rome"]`},
+		{FormatPersistence, `{"rome": "https://wttr.in/Rome"`},
+		{FormatJSONLines, strings.Repeat("x", maxBulkLineSize+1)},
+	}
+
+	for _, test := range tests {
+		sut := newBulkTestShortener()
+
+		if _, err := sut.Import(strings.NewReader(test.data), test.format); !errors.Is(err, ErrMalformedBulk) {
+			t.Errorf("Incorrect error for %s %.20q, got: %v, want: %v.", test.format, test.data, err, ErrMalformedBulk)
+		}
+	}
+}
+
+func TestImportJSONFormats(t *testing.T) {
+	tests := []struct {
+		format      BulkFormat
+		data        string
+		wantInvalid []int
+	}{
+		{FormatJSONLines, "{\"code\":\"rome\",\"url\":\"https://wttr.in/Rome\",\"interstitial\":true}\n\n{\"url\":\"https://wttr.in/Florence\"}\n{\"code\":\n", []int{4}},
+		{FormatPersistence, `{"rome":{"url":"https://wttr.in/Rome","interstitial":true},"milan":"https://wttr.in/Milan","bad":42}`, []int{3}},
+	}
+
+	for _, test := range tests {
+		sut := newBulkTestShortener()
+
+		report, err := sut.Import(strings.NewReader(test.data), test.format)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if report.Imported != 2 {
+			t.Errorf("Incorrect imported from %s, got: %v, want: %v.", test.format, report.Imported, 2)
+		}
+
+		if got := issueRows(report.Invalid); !reflect.DeepEqual(got, test.wantInvalid) {
+			t.Errorf("Incorrect invalid rows from %s, got: %v, want: %v.", test.format, report.Invalid, test.wantInvalid)
+		}
+
+		if link, err := sut.GetLink("rome"); err != nil || !link.Interstitial {
+			t.Errorf("Incorrect imported link from %s, got: %v, %v.", test.format, link, err)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	source := newBulkTestShortener()
+	source.putLink("rome", Link{URL: "https://wttr.in/Rome", CreatedAt: source.now(), ExpiresAt: source.now().Add(time.Hour), Owner: "alice", RedirectCode: 307, Interstitial: true})
+	source.putLink("gone", Link{URL: "https://wttr.in/Turin", ExpiresAt: source.now().Add(-time.Hour)})
+
+	for _, format := range []BulkFormat{FormatCSV, FormatJSONLines, FormatPersistence} {
+		builder := strings.Builder{}
+
+		count, err := source.Export(&builder, format)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if count != 2 {
+			t.Errorf("Incorrect exported count in %s, got: %v, want: %v.", format, count, 2)
+		}
+
+		sut := NewURLShortener()
+		sut.now = source.now
+
+		report, err := sut.Import(strings.NewReader(builder.String()), format)
+
+		if err != nil || report.Imported != 2 || len(report.Invalid) != 0 {
+			t.Fatalf("Incorrect import of %s, got: %v, %v.", builder.String(), report, err)
+		}
+
+		for _, code := range []string{"rome", "taken"} {
+			want, _ := source.GetLink(code)
+			got, err := sut.GetLink(code)
+
+			if err != nil || got.URL != want.URL || !got.CreatedAt.Equal(want.CreatedAt) || !got.ExpiresAt.Equal(want.ExpiresAt) ||
+				got.Owner != want.Owner || got.RedirectCode != want.RedirectCode || got.Interstitial != want.Interstitial {
+				t.Errorf("Incorrect %s round trip of %s, got: %v, want: %v.", format, code, got, want)
+			}
+		}
+	}
+}
+
+func TestImportWAL(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "persistence.wal")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer wal.Close()
+
+	sut := NewURLShortener(WithWAL(wal))
+
+	if _, err := sut.Import(strings.NewReader(testBulkCSV), FormatCSV); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	f, err := os.Open(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer f.Close()
+
+	replayed := NewURLShortener()
+
+	if _, err := replayed.ReplayWAL(bufio.NewReader(f)); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if link, err := replayed.GetLink("taken"); err != nil || link.URL != "https://wttr.in/Milan" {
+		t.Errorf("Incorrect replayed link, got: %v, %v.", link, err)
+	}
+}
+
+// failingStore a MemoryStore whose writes fail once puts are exhausted
+type failingStore struct {
+	*MemoryStore
+	puts int
+}
+
+var errDiskFull = errors.New("disk full")
+
+func (s *failingStore) Put(shortURL string, link Link) error {
+	if s.puts == 0 {
+		return errDiskFull
+	}
+
+	s.puts--
+	return s.MemoryStore.Put(shortURL, link)
+}
+
+func TestImportStorageFailure(t *testing.T) {
+	sut := NewURLShortener(WithStore(&failingStore{NewMemoryStore(), 1}))
+
+	data := "url\nhttps://wttr.in/Rome\nftp://wttr.in/Turin\nhttps://wttr.in/Milan\nhttps://wttr.in/Naples\n"
+
+	report, err := sut.Import(strings.NewReader(data), FormatCSV)
+
+	if !errors.Is(err, errDiskFull) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, errDiskFull)
+	}
+
+	if report.Imported != 1 || len(report.Invalid) != 1 {
+		t.Errorf("Incorrect report before the failure, got: %+v.", report)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("POST", "/api/bulk?format=csv", "", data))
+
+	if responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestBulkHandler(t *testing.T) {
+	keys, _ := LoadAPIKeys(strings.NewReader(testAPIKeys))
+
+	tests := []struct {
+		method         string
+		target         string
+		contentType    string
+		body           string
+		wantStatusCode int
+	}{
+		{"POST", "/api/bulk", "text/csv", testBulkCSV, http.StatusOK},
+		{"POST", "/api/bulk?format=jsonl", "text/plain", `{"code":"rome","url":"https://wttr.in/Rome"}`, http.StatusOK},
+		{"POST", "/api/bulk", "application/json; charset=utf-8", `{"rome":"https://wttr.in/Rome"}`, http.StatusOK},
+		{"POST", "/api/bulk", "application/xml", "<links/>", http.StatusUnsupportedMediaType},
+		{"POST", "/api/bulk", "application/json", "[]", http.StatusBadRequest},
+		{"GET", "/api/bulk", "", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener(WithAPIKeys(keys))
+
+		request := newAuthenticatedRequest(test.method, test.target, "b0b-s3cr3t", test.body)
+		request.Header.Set("Content-Type", test.contentType)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s %s, got: %v, want: %v.", test.method, test.target, responseRecorder.Code, test.wantStatusCode)
+		}
+
+		if responseRecorder.Code != http.StatusOK {
+			continue
+		}
+
+		var report ImportReport
+		if err := json.NewDecoder(responseRecorder.Body).Decode(&report); err != nil || report.Imported == 0 {
+			t.Errorf("Incorrect report, got: %v, %v.", report, err)
+		}
+
+		if link, err := sut.GetLink("rome"); err != nil || link.Owner != "bob" {
+			t.Errorf("Incorrect owner of imported link, got: %v, %v.", link, err)
+		}
+	}
+
+	sut := NewURLShortener(WithAPIKeys(keys))
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("POST", "/api/bulk", "", testBulkCSV))
+
+	if responseRecorder.Code != http.StatusUnauthorized {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestImportBatches(t *testing.T) {
+	sut := NewURLShortener()
+
+	data := strings.Builder{}
+	data.WriteString("url\n")
+
+	rows := 2*importBatchSize + 1
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&data, "https://wttr.in/%d\n", i)
+	}
+
+	report, err := sut.Import(strings.NewReader(data.String()), FormatCSV)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if report.Imported != rows {
+		t.Errorf("Incorrect imported rows, got: %v, want: %v.", report.Imported, rows)
+	}
+
+	if sut.statistics.ServerStats.TotalURL != int64(rows) {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, rows)
+	}
+}
+
+func TestBulkHandlerRateLimit(t *testing.T) {
+	sut := NewURLShortener(WithCreateRateLimit(RateLimit{Rate: 0.1, Burst: 3}))
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		body           string
+		wantStatusCode int
+	}{
+		{"url\nhttps://wttr.in/Rome\nhttps://wttr.in/Milan\nhttps://wttr.in/Turin\nhttps://wttr.in/Naples\n", http.StatusRequestEntityTooLarge},
+		{"url\nhttps://wttr.in/Rome\nhttps://wttr.in/Milan\n", http.StatusOK},
+		{"url\nhttps://wttr.in/Turin\nhttps://wttr.in/Naples\n", http.StatusTooManyRequests},
+		{"url\n", http.StatusTooManyRequests},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+		sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("POST", "/api/bulk?format=csv", "", test.body))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %q, got: %v, want: %v.", test.body, responseRecorder.Code, test.wantStatusCode)
+		}
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 2 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 2)
+	}
+}
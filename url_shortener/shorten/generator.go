@@ -0,0 +1,345 @@
+package shorten
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// CodeGenerator generates the short URLs of long URLs
+type CodeGenerator interface {
+	// Generate returns a short URL candidate for longURL. The attempt starts
+	// from 0 and is incremented each time the candidate returned is already
+	// owned by a different long URL.
+	Generate(longURL string, attempt int) (string, error)
+}
+
+// maxSalts bounds the re-salting of a URL whose hashes all collide
+const maxSalts = 16
+
+// seeder is implemented by the generators whose state has to follow the
+// codes in use, it is seeded whenever links are loaded
+type seeder interface {
+	seed(taken func(code string) bool)
+}
+
+// ErrUnknownGenerator returned by NewCodeGenerator for generators not known
+var ErrUnknownGenerator = errors.New("unknown generator")
+
+// GeneratorConfig selects and configures the CodeGenerator built by
+// NewCodeGenerator
+type GeneratorConfig struct {
+	// Name the generator: hash, counter or random
+	Name string
+	// Alphabet the characters of the counter and random short URLs
+	Alphabet string
+	// ExcludeLookAlikes drops the LookAlikeCharacters from the alphabet
+	ExcludeLookAlikes bool
+	// CodeLength the length of the random short URLs
+	CodeLength int
+	// CounterFile the persistence JSON file of the counter state
+	CounterFile string
+}
+
+// NewCodeGenerator builds the generator of the configuration. The counter
+// is returned too when selected, as its state has to be persisted, loaded
+// from the counter file unless missing.
+func NewCodeGenerator(config GeneratorConfig) (CodeGenerator, *CounterGenerator, error) {
+	exclude := ""
+	if config.ExcludeLookAlikes {
+		exclude = LookAlikeCharacters
+	}
+
+	characters, err := NewAlphabet(config.Alphabet, exclude)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error in alphabet: %w", err)
+	}
+
+	switch config.Name {
+	case "hash":
+		return NewHashGenerator(), nil, nil
+	case "counter":
+		counter := NewCounterGenerator(characters)
+
+		if err := counter.unpersistFile(config.CounterFile); err != nil {
+			return nil, nil, err
+		}
+
+		return counter, counter, nil
+	case "random":
+		random, err := NewRandomGenerator(characters, config.CodeLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error in random generator: %w", err)
+		}
+
+		return random, nil, nil
+	}
+
+	return nil, nil, fmt.Errorf("%w: %s", ErrUnknownGenerator, config.Name)
+}
+
+// errCodeSpaceExhausted is returned when a generator has no candidates left
+var errCodeSpaceExhausted = errors.New("no free short URL left")
+
+// Alphabet the set of characters short URLs are made of
+type Alphabet string
+
+// Alphabets and characters ready to use
+const (
+	Base62Alphabet Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// LookAlikeCharacters are easily confused when a short URL is read aloud
+	// or printed
+	LookAlikeCharacters = "0O1Il"
+)
+
+// urlSafeCharacters are the characters allowed unescaped in a URL path
+const urlSafeCharacters = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-._~"
+
+// NewAlphabet an Alphabet constructor, it keeps the characters in order
+// dropping duplicates and the ones in exclude
+func NewAlphabet(characters, exclude string) (Alphabet, error) {
+	builder := strings.Builder{}
+
+	for _, character := range characters {
+		if !strings.ContainsRune(urlSafeCharacters, character) {
+			return "", fmt.Errorf("character not allowed in alphabet: %q", character)
+		}
+
+		if strings.ContainsRune(exclude, character) {
+			continue
+		}
+
+		if strings.ContainsRune(builder.String(), character) {
+			continue
+		}
+
+		builder.WriteRune(character)
+	}
+
+	alphabet := Alphabet(builder.String())
+
+	if len(alphabet) < 2 {
+		return "", fmt.Errorf("alphabet needs at least 2 characters, got: %q", alphabet)
+	}
+
+	return alphabet, nil
+}
+
+// encode returns the representation of n in the positional system whose
+// digits are the alphabet characters
+func (a Alphabet) encode(n uint64) string {
+	base := uint64(len(a))
+
+	if n == 0 {
+		return string(a[0])
+	}
+
+	digits := make([]byte, 0, 11)
+
+	for ; n > 0; n /= base {
+		digits = append(digits, a[n%base])
+	}
+
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		digits[i], digits[j] = digits[j], digits[i]
+	}
+
+	return string(digits)
+}
+
+// HashGenerator generates short URLs from the SHA-1 digest of long URLs, it
+// is the default CodeGenerator
+type HashGenerator struct {
+	hasher func(string) string
+}
+
+// NewHashGenerator a HashGenerator constructor
+func NewHashGenerator() *HashGenerator {
+	hashGenerator := HashGenerator{}
+
+	hashGenerator.hasher = hexDigest
+
+	return &hashGenerator
+}
+
+// Generate returns the digest prefix returned by Shorten at the first
+// attempt. On the following attempts the prefix is extended one character at
+// a time and, once the whole digest is used, the URL is re-salted and hashed
+// again. The sequence is deterministic, so existing short URLs stay stable
+// and the same URL always lands on the same short URL.
+func (g *HashGenerator) Generate(longURL string, attempt int) (string, error) {
+	for salt := 0; salt < maxSalts; salt++ {
+		input := longURL
+		if salt > 0 {
+			input = fmt.Sprintf("%s#%d", longURL, salt)
+		}
+
+		digest := g.hasher(input)
+
+		candidates := len(digest) - shortURLLength + 1
+		if candidates < 0 {
+			candidates = 0
+		}
+
+		if attempt < candidates {
+			return digest[:shortURLLength+attempt], nil
+		}
+
+		attempt -= candidates
+	}
+
+	return "", errCodeSpaceExhausted
+}
+
+// CounterGenerator generates sequential short URLs encoding a monotonic
+// counter with an Alphabet
+type CounterGenerator struct {
+	next     uint64
+	alphabet Alphabet
+}
+
+type counterJSON struct {
+	Next uint64 `json:"next"`
+}
+
+// NewCounterGenerator a CounterGenerator constructor, the counter starts
+// from zero
+func NewCounterGenerator(alphabet Alphabet) *CounterGenerator {
+	counterGenerator := CounterGenerator{}
+
+	counterGenerator.alphabet = alphabet
+
+	return &counterGenerator
+}
+
+// Generate returns the next value of the counter, a collision just moves on
+// to the following one
+func (g *CounterGenerator) Generate(longURL string, attempt int) (string, error) {
+	n := atomic.AddUint64(&g.next, 1) - 1
+
+	return g.alphabet.encode(n), nil
+}
+
+// seed moves the counter past the consecutive values already taken, the
+// codes handed out after the last persisted state
+func (g *CounterGenerator) seed(taken func(code string) bool) {
+	for {
+		n := atomic.LoadUint64(&g.next)
+
+		if !taken(g.alphabet.encode(n)) {
+			return
+		}
+
+		atomic.CompareAndSwapUint64(&g.next, n, n+1)
+	}
+}
+
+// UnpersistFrom function reads and decodes the counter state from the
+// reader passed in
+func (g *CounterGenerator) UnpersistFrom(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+
+	var counter counterJSON
+
+	if err := decoder.Decode(&counter); err != nil {
+		return err
+	}
+
+	atomic.StoreUint64(&g.next, counter.Next)
+	return nil
+}
+
+// unpersistFile loads the counter state from the file at path, a missing
+// file leaves the counter from zero
+func (g *CounterGenerator) unpersistFile(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := g.UnpersistFrom(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("error unpersisting counter %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// PersistTo function encodes the counter state in a JSON written to the
+// writer passed in
+func (g *CounterGenerator) PersistTo(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	counter := counterJSON{atomic.LoadUint64(&g.next)}
+
+	return encoder.Encode(&counter)
+}
+
+// SnapshotTo function atomically replaces the file at path with the counter
+// state. Should the state on disk lag behind the links after a crash, the
+// URLShortener moves it past the codes in use when loading them.
+func (g *CounterGenerator) SnapshotTo(path string) error {
+	_, err := writeFileAtomically(path, g.PersistTo)
+
+	return err
+}
+
+// RandomGenerator generates unguessable short URLs of random characters
+// taken from an Alphabet
+type RandomGenerator struct {
+	alphabet Alphabet
+	length   int
+	random   io.Reader
+}
+
+// NewRandomGenerator a RandomGenerator constructor, it uses a
+// cryptographically secure random source
+func NewRandomGenerator(alphabet Alphabet, length int) (*RandomGenerator, error) {
+	if length < 1 {
+		return nil, fmt.Errorf("invalid short URL length: %v", length)
+	}
+
+	randomGenerator := RandomGenerator{}
+
+	randomGenerator.alphabet = alphabet
+	randomGenerator.length = length
+	randomGenerator.random = rand.Reader
+
+	return &randomGenerator, nil
+}
+
+// Generate returns a new random short URL at each attempt
+func (g *RandomGenerator) Generate(longURL string, attempt int) (string, error) {
+	base := len(g.alphabet)
+	// bytes from limit up are rejected so every character is equally likely
+	limit := 256 - 256%base
+
+	code := make([]byte, 0, g.length)
+	buffer := make([]byte, g.length)
+
+	for len(code) < g.length {
+		if _, err := io.ReadFull(g.random, buffer); err != nil {
+			return "", err
+		}
+
+		for _, b := range buffer {
+			if int(b) >= limit || len(code) == g.length {
+				continue
+			}
+
+			code = append(code, g.alphabet[int(b)%base])
+		}
+	}
+
+	return string(code), nil
+}
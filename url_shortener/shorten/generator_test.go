@@ -0,0 +1,247 @@
+package shorten
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewAlphabet(t *testing.T) {
+	tests := []struct {
+		characters   string
+		exclude      string
+		wantAlphabet Alphabet
+		wantErr      bool
+	}{
+		{string(Base62Alphabet), "", Base62Alphabet, false},
+		{"0123456789", LookAlikeCharacters, "23456789", false},
+		{"aabbcc", "", "abc", false},
+		{"ab/c", "", "", true},
+		{"aaa", "", "", true},
+		{"01", LookAlikeCharacters, "", true},
+	}
+
+	for _, test := range tests {
+		alphabet, err := NewAlphabet(test.characters, test.exclude)
+
+		if !test.wantErr && err != nil {
+			t.Errorf("Unexpected error but got: %s.", err)
+		}
+
+		if test.wantErr && err == nil {
+			t.Errorf("Expected error for %q but got nil.", test.characters)
+		}
+
+		if alphabet != test.wantAlphabet {
+			t.Errorf("Incorrect alphabet, got: %q, want: %q.", alphabet, test.wantAlphabet)
+		}
+	}
+}
+
+func TestAlphabetEncode(t *testing.T) {
+	tests := []struct {
+		alphabet Alphabet
+		n        uint64
+		want     string
+	}{
+		{Base62Alphabet, 0, "0"},
+		{Base62Alphabet, 61, "z"},
+		{Base62Alphabet, 62, "10"},
+		{Base62Alphabet, 3843, "zz"},
+		{"ab", 5, "bab"},
+	}
+
+	for _, test := range tests {
+		got := test.alphabet.encode(test.n)
+		if got != test.want {
+			t.Errorf("Incorrect encoding of %v, got: %s, want: %s.", test.n, got, test.want)
+		}
+	}
+}
+
+func TestHashGenerator(t *testing.T) {
+	sut := NewHashGenerator()
+
+	longURL := "https://github.com/develersrl/powersoft-hmi"
+	digest := hexDigest(longURL)
+
+	tests := []struct {
+		attempt int
+		want    string
+	}{
+		{0, Shorten(longURL)},
+		{1, digest[:8]},
+		{33, digest},
+		{34, hexDigest(longURL + "#1")[:7]},
+	}
+
+	for _, test := range tests {
+		got, err := sut.Generate(longURL, test.attempt)
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if got != test.want {
+			t.Errorf("Incorrect short URL at attempt %v, got: %s, want: %s.", test.attempt, got, test.want)
+		}
+	}
+
+	if _, err := sut.Generate(longURL, 34*maxSalts); err != errCodeSpaceExhausted {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, errCodeSpaceExhausted)
+	}
+}
+
+func TestCounterGenerator(t *testing.T) {
+	sut := NewCounterGenerator(Base62Alphabet)
+
+	for _, want := range []string{"0", "1", "2"} {
+		got, _ := sut.Generate("https://a.example", 0)
+		if got != want {
+			t.Errorf("Incorrect short URL, got: %s, want: %s.", got, want)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := sut.PersistTo(&buffer); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	restored := NewCounterGenerator(Base62Alphabet)
+	if err := restored.UnpersistFrom(&buffer); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if got, _ := restored.Generate("https://a.example", 0); got != "3" {
+		t.Errorf("Incorrect short URL after restart, got: %s, want: %s.", got, "3")
+	}
+}
+
+func TestCounterGeneratorSkipsCollisions(t *testing.T) {
+	sut := NewURLShortener(WithCodeGenerator(NewCounterGenerator(Base62Alphabet)))
+	sut.addURL("https://a.example", "0")
+	sut.addURL("https://b.example", "1")
+
+	shortURL, _, err := sut.shortenURL(Link{URL: "https://c.example"})
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if shortURL != "2" {
+		t.Errorf("Incorrect short URL, got: %s, want: %s.", shortURL, "2")
+	}
+}
+
+func TestCounterGeneratorCatchesUpOnLoad(t *testing.T) {
+	counter := NewCounterGenerator(Base62Alphabet)
+	source := NewURLShortener(WithCodeGenerator(counter))
+
+	for i := 0; i < 2*maxAttempts; i++ {
+		if _, _, err := source.shortenURL(Link{URL: fmt.Sprintf("https://%d.example", i)}); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := source.PersistTo(&buffer); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	// the counter state on disk lags behind as after a crash
+	sut := NewURLShortener(WithCodeGenerator(NewCounterGenerator(Base62Alphabet)))
+	if err := sut.UnpersistFrom(&buffer); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	shortURL, _, err := sut.shortenURL(Link{URL: "https://new.example"})
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if want := Base62Alphabet.encode(2 * maxAttempts); shortURL != want {
+		t.Errorf("Incorrect short URL, got: %s, want: %s.", shortURL, want)
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	alphabet, _ := NewAlphabet(string(Base62Alphabet), LookAlikeCharacters)
+
+	sut, err := NewRandomGenerator(alphabet, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		got, err := sut.Generate("https://a.example", i)
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if len(got) != 10 {
+			t.Errorf("Incorrect short URL length, got: %v, want: %v.", len(got), 10)
+		}
+
+		if strings.ContainsAny(got, LookAlikeCharacters) {
+			t.Errorf("Unexpected look-alike character in: %s.", got)
+		}
+
+		seen[got] = true
+	}
+
+	if len(seen) != 100 {
+		t.Errorf("Incorrect number of distinct short URLs, got: %v, want: %v.", len(seen), 100)
+	}
+
+	if _, err := NewRandomGenerator(alphabet, 0); err == nil {
+		t.Error("Expected error for zero length but got nil.")
+	}
+}
+
+func TestNewCodeGenerator(t *testing.T) {
+	counterFile := filepath.Join(t.TempDir(), "counter.json")
+	if err := ioutil.WriteFile(counterFile, []byte(`{"next":42}`), 0644); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	tests := []struct {
+		config      GeneratorConfig
+		wantCounter bool
+		wantCode    string
+		wantError   error
+	}{
+		{GeneratorConfig{Name: "hash", Alphabet: string(Base62Alphabet)}, false, "4611ce1", nil},
+		{GeneratorConfig{Name: "counter", Alphabet: string(Base62Alphabet), CounterFile: counterFile}, true, "g", nil},
+		{GeneratorConfig{Name: "counter", Alphabet: string(Base62Alphabet), CounterFile: counterFile + ".missing"}, true, "0", nil},
+		{GeneratorConfig{Name: "counter", Alphabet: "01", ExcludeLookAlikes: true}, false, "", nil},
+		{GeneratorConfig{Name: "random", Alphabet: string(Base62Alphabet), CodeLength: 0}, false, "", nil},
+		{GeneratorConfig{Name: "sequence", Alphabet: string(Base62Alphabet)}, false, "", ErrUnknownGenerator},
+	}
+
+	for _, test := range tests {
+		generator, counter, err := NewCodeGenerator(test.config)
+
+		if test.wantCode == "" {
+			if err == nil || (test.wantError != nil && !errors.Is(err, test.wantError)) {
+				t.Errorf("Incorrect error for %+v, got: %v, want: %v.", test.config, err, test.wantError)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if (counter != nil) != test.wantCounter {
+			t.Errorf("Incorrect counter for %+v, got: %v, want: %v.", test.config, counter != nil, test.wantCounter)
+		}
+
+		if code, _ := generator.Generate("https://github.com/develersrl/powersoft-hmi", 0); code != test.wantCode {
+			t.Errorf("Incorrect short URL for %+v, got: %s, want: %s.", test.config, code, test.wantCode)
+		}
+	}
+}
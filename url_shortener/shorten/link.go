@@ -0,0 +1,145 @@
+package shorten
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors about link expiration
+var (
+	ErrExpired       = errors.New("short URL expired")
+	ErrInvalidExpiry = errors.New("invalid expiry")
+)
+
+// Link the long URL a short URL points to, together with its metadata
+type Link struct {
+	URL       string
+	CreatedAt time.Time
+	// ExpiresAt is the zero time for links that never expire
+	ExpiresAt time.Time
+	// Owner is the name of the API key that created the link, empty when
+	// API keys are not required
+	Owner string
+	// RedirectCode is the status code of the redirects, zero for the server
+	// default
+	RedirectCode int
+	// Interstitial links show a page naming the destination instead of
+	// redirecting straight to it
+	Interstitial bool
+}
+
+// linkFileJSON is how a Link is persisted, zero times are left out
+type linkFileJSON struct {
+	URL          string     `json:"url"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+	RedirectCode int        `json:"redirect_code,omitempty"`
+	Interstitial bool       `json:"interstitial,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+// fileJSON returns the persisted form of the link
+func (l Link) fileJSON() linkFileJSON {
+	return linkFileJSON{
+		URL:          l.URL,
+		CreatedAt:    optionalTime(l.CreatedAt),
+		ExpiresAt:    optionalTime(l.ExpiresAt),
+		Owner:        l.Owner,
+		RedirectCode: l.RedirectCode,
+		Interstitial: l.Interstitial,
+	}
+}
+
+// link converts the persisted form back to a Link
+func (l linkFileJSON) link() Link {
+	link := Link{URL: l.URL, Owner: l.Owner, RedirectCode: l.RedirectCode, Interstitial: l.Interstitial}
+
+	if l.CreatedAt != nil {
+		link.CreatedAt = *l.CreatedAt
+	}
+
+	if l.ExpiresAt != nil {
+		link.ExpiresAt = *l.ExpiresAt
+	}
+
+	return link
+}
+
+// MarshalJSON encodes the link as a JSON object
+func (l Link) MarshalJSON() ([]byte, error) {
+	link := l.fileJSON()
+
+	return json.Marshal(&link)
+}
+
+// UnmarshalJSON decodes the link from a JSON object or, as persistence files
+// written before links had metadata do, from a bare long URL string
+func (l *Link) UnmarshalJSON(data []byte) error {
+	var longURL string
+
+	if err := json.Unmarshal(data, &longURL); err == nil {
+		*l = Link{URL: longURL}
+		return nil
+	}
+
+	var link linkFileJSON
+
+	if err := json.Unmarshal(data, &link); err != nil {
+		return err
+	}
+
+	*l = link.link()
+
+	return nil
+}
+
+// sameTarget tells if two links differ at most in their creation time and
+// neither expires, so one can stand for the other
+func (l Link) sameTarget(other Link) bool {
+	return l.URL == other.URL && l.Owner == other.Owner && l.RedirectCode == other.RedirectCode &&
+		l.Interstitial == other.Interstitial && l.ExpiresAt.IsZero() && other.ExpiresAt.IsZero()
+}
+
+// isExpired tells if the link is expired at the time passed in
+func (l Link) isExpired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// parseExpiry returns the expiry time of a link either from a time to live,
+// as in 72h, or from an absolute RFC 3339 time. Both empty mean no expiry.
+func parseExpiry(now time.Time, ttl, expiresAt string) (time.Time, error) {
+	switch {
+	case ttl != "" && expiresAt != "":
+		return time.Time{}, fmt.Errorf("%w: ttl and expires_at are mutually exclusive", ErrInvalidExpiry)
+	case ttl != "":
+		duration, err := time.ParseDuration(ttl)
+		if err != nil || duration <= 0 {
+			return time.Time{}, fmt.Errorf("%w: ttl must be a positive duration: %s", ErrInvalidExpiry, ttl)
+		}
+
+		return now.Add(duration), nil
+	case expiresAt != "":
+		expiry, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: expires_at must be an RFC 3339 time: %s", ErrInvalidExpiry, expiresAt)
+		}
+
+		if !expiry.After(now) {
+			return time.Time{}, fmt.Errorf("%w: expires_at is in the past: %s", ErrInvalidExpiry, expiresAt)
+		}
+
+		return expiry, nil
+	}
+
+	return time.Time{}, nil
+}
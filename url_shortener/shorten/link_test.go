@@ -0,0 +1,186 @@
+package shorten
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkJSON(t *testing.T) {
+	createdAt := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(72 * time.Hour)
+
+	tests := []struct {
+		data     string
+		wantLink Link
+	}{
+		{`"https://wttr.in/Florence"`, Link{URL: "https://wttr.in/Florence"}},
+		{`{"url":"https://wttr.in/Florence"}`, Link{URL: "https://wttr.in/Florence"}},
+		{`{"url":"https://wttr.in/Florence","created_at":"2020-09-08T10:00:00Z","expires_at":"2020-09-11T10:00:00Z"}`, Link{URL: "https://wttr.in/Florence", CreatedAt: createdAt, ExpiresAt: expiresAt}},
+		{`{"url":"https://wttr.in/Florence","interstitial":true}`, Link{URL: "https://wttr.in/Florence", Interstitial: true}},
+	}
+
+	for _, test := range tests {
+		var link Link
+
+		if err := json.Unmarshal([]byte(test.data), &link); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if !link.CreatedAt.Equal(test.wantLink.CreatedAt) || !link.ExpiresAt.Equal(test.wantLink.ExpiresAt) || link.URL != test.wantLink.URL ||
+			link.Interstitial != test.wantLink.Interstitial {
+			t.Errorf("Incorrect link from %s, got: %v, want: %v.", test.data, link, test.wantLink)
+		}
+
+		data, _ := json.Marshal(link)
+
+		var roundTrip Link
+		json.Unmarshal(data, &roundTrip)
+
+		if !roundTrip.ExpiresAt.Equal(link.ExpiresAt) || roundTrip.URL != link.URL || roundTrip.Interstitial != link.Interstitial {
+			t.Errorf("Incorrect link after round trip, got: %v, want: %v.", roundTrip, link)
+		}
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		ttl        string
+		expiresAt  string
+		wantExpiry time.Time
+		wantErr    bool
+	}{
+		{"", "", time.Time{}, false},
+		{"72h", "", now.Add(72 * time.Hour), false},
+		{"", "2020-09-09T10:00:00Z", now.Add(24 * time.Hour), false},
+		{"0s", "", time.Time{}, true},
+		{"-1h", "", time.Time{}, true},
+		{"tomorrow", "", time.Time{}, true},
+		{"", "2020-09-07T10:00:00Z", time.Time{}, true},
+		{"", "09/09/2020", time.Time{}, true},
+		{"1h", "2020-09-09T10:00:00Z", time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		expiry, err := parseExpiry(now, test.ttl, test.expiresAt)
+
+		if !test.wantErr && err != nil {
+			t.Errorf("Unexpected error but got: %s.", err)
+		}
+
+		if test.wantErr && !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("Incorrect error for ttl %q and expires_at %q, got: %v, want: %v.", test.ttl, test.expiresAt, err, ErrInvalidExpiry)
+		}
+
+		if !expiry.Equal(test.wantExpiry) {
+			t.Errorf("Incorrect expiry, got: %v, want: %v.", expiry, test.wantExpiry)
+		}
+	}
+}
+
+func TestExpiredLinks(t *testing.T) {
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return now
+	}
+
+	request := httptest.NewRequest("GET", "/shorten?url=https://wttr.in/Rome&ttl=1h", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.shortenHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
+
+	shortURL := Shorten("https://wttr.in/Rome")
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	now = now.Add(2 * time.Hour)
+
+	request = httptest.NewRequest("GET", "/"+shortURL, nil)
+	responseRecorder = httptest.NewRecorder()
+
+	sut.expanderHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusGone {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusGone)
+	}
+
+	if _, err := sut.GetURL(shortURL); !errors.Is(err, ErrExpired) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrExpired)
+	}
+
+	removed, err := sut.RemoveExpired()
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if removed != 1 {
+		t.Errorf("Incorrect removed links, got: %v, want: %v.", removed, 1)
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 1 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 1)
+	}
+
+	if _, err := sut.GetURL(shortURL); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrNotFound)
+	}
+}
+
+func TestExpiredLinkIsFree(t *testing.T) {
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return now
+	}
+
+	if _, err := sut.aliasURL("team-wiki", Link{URL: "https://wiki.example", ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if _, err := sut.aliasURL("team-wiki", Link{URL: "https://other.example"}); !errors.Is(err, ErrAliasTaken) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrAliasTaken)
+	}
+
+	now = now.Add(time.Hour)
+
+	if _, err := sut.aliasURL("team-wiki", Link{URL: "https://other.example"}); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL, _ := sut.GetURL("team-wiki"); longURL != "https://other.example" {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", longURL, "https://other.example")
+	}
+}
+
+func TestUnpersistFromMixedFormats(t *testing.T) {
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	data := `{"f495791":"https://wttr.in/Florence","87aefef":{"url":"https://wttr.in/Rome","expires_at":"2020-09-08T09:00:00Z"}}`
+
+	if err := sut.UnpersistFrom(strings.NewReader(data)); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL, _ := sut.GetURL("f495791"); longURL != "https://wttr.in/Florence" {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", longURL, "https://wttr.in/Florence")
+	}
+
+	if _, err := sut.GetURL("87aefef"); !errors.Is(err, ErrExpired) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrExpired)
+	}
+}
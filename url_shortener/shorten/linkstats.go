@@ -0,0 +1,261 @@
+package shorten
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bounds of the per link analytics, so a link cannot exhaust memory
+const (
+	maxDailyClicks    = 90
+	maxDistinctSource = 100
+	maxUserAgentSize  = 200
+	topSources        = 10
+
+	otherSource    = "(other)"
+	directReferrer = "(direct)"
+	unknownAgent   = "(unknown)"
+
+	dayLayout = "2006-01-02"
+)
+
+// linkAnalytics keeps the click analytics of every short URL. A sync.Map is
+// used as links are added once and read on every redirect.
+type linkAnalytics struct {
+	links sync.Map // short URL -> *linkStats
+}
+
+// linkStats the click analytics of a short URL: counters are atomic, the
+// breakdowns are guarded by a per link mutex so redirects of different links
+// never contend
+type linkStats struct {
+	clicks      int64
+	firstAccess int64 // Unix nanoseconds
+	lastAccess  int64 // Unix nanoseconds
+
+	mux        sync.Mutex
+	daily      map[string]int64
+	referrers  map[string]int64
+	userAgents map[string]int64
+}
+
+type linkStatsJSON struct {
+	Code          string      `json:"code"`
+	Clicks        int64       `json:"clicks"`
+	FirstAccess   *time.Time  `json:"first_access,omitempty"`
+	LastAccess    *time.Time  `json:"last_access,omitempty"`
+	ClicksPerDay  []countJSON `json:"clicks_per_day"`
+	TopReferrers  []countJSON `json:"top_referrers"`
+	TopUserAgents []countJSON `json:"top_user_agents"`
+}
+
+type countJSON struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+func newLinkStats() *linkStats {
+	stats := linkStats{}
+
+	stats.daily = make(map[string]int64)
+	stats.referrers = make(map[string]int64)
+	stats.userAgents = make(map[string]int64)
+
+	return &stats
+}
+
+// record accounts a redirect of shortURL
+func (a *linkAnalytics) record(shortURL string, r *http.Request, now time.Time) {
+	value, ok := a.links.Load(shortURL)
+	if !ok {
+		value, _ = a.links.LoadOrStore(shortURL, newLinkStats())
+	}
+
+	stats := value.(*linkStats)
+	nanos := now.UnixNano()
+
+	atomic.AddInt64(&stats.clicks, 1)
+	atomic.CompareAndSwapInt64(&stats.firstAccess, 0, nanos)
+	atomic.StoreInt64(&stats.lastAccess, nanos)
+
+	stats.mux.Lock()
+	defer stats.mux.Unlock()
+
+	stats.addDay(now.UTC().Format(dayLayout))
+	addSource(stats.referrers, referrerSource(r.Referer()))
+	addSource(stats.userAgents, userAgentSource(r.UserAgent()))
+}
+
+// forget drops the analytics of shortURL
+func (a *linkAnalytics) forget(shortURL string) {
+	a.links.Delete(shortURL)
+}
+
+// clicks returns the clicks of shortURL
+func (a *linkAnalytics) clicks(shortURL string) int64 {
+	value, ok := a.links.Load(shortURL)
+	if !ok {
+		return 0
+	}
+
+	return atomic.LoadInt64(&value.(*linkStats).clicks)
+}
+
+// snapshot returns the analytics of shortURL, empty when never accessed
+func (a *linkAnalytics) snapshot(shortURL string) linkStatsJSON {
+	snapshot := linkStatsJSON{Code: shortURL}
+	snapshot.ClicksPerDay = make([]countJSON, 0)
+	snapshot.TopReferrers = make([]countJSON, 0)
+	snapshot.TopUserAgents = make([]countJSON, 0)
+
+	value, ok := a.links.Load(shortURL)
+	if !ok {
+		return snapshot
+	}
+
+	stats := value.(*linkStats)
+
+	snapshot.Clicks = atomic.LoadInt64(&stats.clicks)
+	snapshot.FirstAccess = optionalTime(unixNanoTime(atomic.LoadInt64(&stats.firstAccess)))
+	snapshot.LastAccess = optionalTime(unixNanoTime(atomic.LoadInt64(&stats.lastAccess)))
+
+	stats.mux.Lock()
+	defer stats.mux.Unlock()
+
+	for day, count := range stats.daily {
+		snapshot.ClicksPerDay = append(snapshot.ClicksPerDay, countJSON{day, count})
+	}
+
+	sort.Slice(snapshot.ClicksPerDay, func(i, j int) bool {
+		return snapshot.ClicksPerDay[i].Name < snapshot.ClicksPerDay[j].Name
+	})
+
+	snapshot.TopReferrers = topCounts(stats.referrers)
+	snapshot.TopUserAgents = topCounts(stats.userAgents)
+
+	return snapshot
+}
+
+// addDay counts a click in the day, dropping the oldest days beyond the
+// retention
+func (s *linkStats) addDay(day string) {
+	s.daily[day]++
+
+	for len(s.daily) > maxDailyClicks {
+		oldest := day
+
+		for candidate := range s.daily {
+			if candidate < oldest {
+				oldest = candidate
+			}
+		}
+
+		delete(s.daily, oldest)
+	}
+}
+
+// addSource counts a source, once too many distinct sources are known the
+// new ones are counted together
+func addSource(sources map[string]int64, source string) {
+	if _, ok := sources[source]; !ok && len(sources) >= maxDistinctSource {
+		source = otherSource
+	}
+
+	sources[source]++
+}
+
+// referrerSource reduces a referrer to its host
+func referrerSource(referrer string) string {
+	if referrer == "" {
+		return directReferrer
+	}
+
+	u, err := url.Parse(referrer)
+	if err != nil || u.Host == "" {
+		return otherSource
+	}
+
+	return strings.ToLower(u.Host)
+}
+
+func userAgentSource(userAgent string) string {
+	if userAgent == "" {
+		return unknownAgent
+	}
+
+	if len(userAgent) > maxUserAgentSize {
+		return userAgent[:maxUserAgentSize]
+	}
+
+	return userAgent
+}
+
+// topCounts returns the most frequent sources, ties sorted by name
+func topCounts(sources map[string]int64) []countJSON {
+	counts := make([]countJSON, 0, len(sources))
+
+	for name, count := range sources {
+		counts = append(counts, countJSON{name, count})
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+
+	if len(counts) > topSources {
+		counts = counts[:topSources]
+	}
+
+	return counts
+}
+
+func unixNanoTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanos).UTC()
+}
+
+func (s linkStatsJSON) String() string {
+	statsBody := &strings.Builder{}
+
+	fmt.Fprintf(statsBody, "Statistics for short URL %s:\n\n", s.Code)
+	fmt.Fprintf(statsBody, "Total clicks: %v\n", s.Clicks)
+
+	if s.FirstAccess != nil {
+		fmt.Fprintf(statsBody, "First access: %s\n", s.FirstAccess.Format(time.RFC3339))
+	}
+
+	if s.LastAccess != nil {
+		fmt.Fprintf(statsBody, "Last access: %s\n", s.LastAccess.Format(time.RFC3339))
+	}
+
+	sections := []struct {
+		title  string
+		counts []countJSON
+	}{
+		{"Clicks per day", s.ClicksPerDay},
+		{"Top referrers", s.TopReferrers},
+		{"Top user agents", s.TopUserAgents},
+	}
+
+	for _, section := range sections {
+		fmt.Fprintf(statsBody, "%s:\n", section.title)
+
+		for _, count := range section.counts {
+			fmt.Fprintf(statsBody, "  %s: %v\n", count.Name, count.Count)
+		}
+	}
+
+	return statsBody.String()
+}
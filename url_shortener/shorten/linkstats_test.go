@@ -0,0 +1,206 @@
+package shorten
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newClickRequest(shortURL, referrer, userAgent string) *http.Request {
+	request := httptest.NewRequest("GET", "/"+shortURL, nil)
+	request.Header.Set("Referer", referrer)
+	request.Header.Set("User-Agent", userAgent)
+
+	return request
+}
+
+func TestLinkAnalyticsRecord(t *testing.T) {
+	var sut linkAnalytics
+
+	day := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	clicks := []struct {
+		referrer  string
+		userAgent string
+		at        time.Time
+	}{
+		{"https://github.com/develersrl", "curl/7.68.0", day},
+		{"https://GitHub.com/other", "curl/7.68.0", day.Add(time.Hour)},
+		{"", "Mozilla/5.0", day.Add(24 * time.Hour)},
+		{"https://news.example/", "", day.Add(25 * time.Hour)},
+	}
+
+	for _, click := range clicks {
+		sut.record("4611ce1", newClickRequest("4611ce1", click.referrer, click.userAgent), click.at)
+	}
+
+	got := sut.snapshot("4611ce1")
+
+	if got.Clicks != 4 {
+		t.Errorf("Incorrect clicks, got: %v, want: %v.", got.Clicks, 4)
+	}
+
+	if got.FirstAccess == nil || !got.FirstAccess.Equal(day) {
+		t.Errorf("Incorrect first access, got: %v, want: %v.", got.FirstAccess, day)
+	}
+
+	if got.LastAccess == nil || !got.LastAccess.Equal(day.Add(25*time.Hour)) {
+		t.Errorf("Incorrect last access, got: %v, want: %v.", got.LastAccess, day.Add(25*time.Hour))
+	}
+
+	wantDays := []countJSON{{"2020-09-08", 2}, {"2020-09-09", 2}}
+	if fmt.Sprint(got.ClicksPerDay) != fmt.Sprint(wantDays) {
+		t.Errorf("Incorrect clicks per day, got: %v, want: %v.", got.ClicksPerDay, wantDays)
+	}
+
+	wantReferrers := []countJSON{{"github.com", 2}, {directReferrer, 1}, {"news.example", 1}}
+	if fmt.Sprint(got.TopReferrers) != fmt.Sprint(wantReferrers) {
+		t.Errorf("Incorrect top referrers, got: %v, want: %v.", got.TopReferrers, wantReferrers)
+	}
+
+	wantUserAgents := []countJSON{{"curl/7.68.0", 2}, {unknownAgent, 1}, {"Mozilla/5.0", 1}}
+	if fmt.Sprint(got.TopUserAgents) != fmt.Sprint(wantUserAgents) {
+		t.Errorf("Incorrect top user agents, got: %v, want: %v.", got.TopUserAgents, wantUserAgents)
+	}
+
+	sut.forget("4611ce1")
+
+	if got := sut.snapshot("4611ce1"); got.Clicks != 0 {
+		t.Errorf("Incorrect clicks after forget, got: %v, want: %v.", got.Clicks, 0)
+	}
+}
+
+func TestLinkAnalyticsBounds(t *testing.T) {
+	var sut linkAnalytics
+
+	day := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxDistinctSource+10; i++ {
+		userAgent := fmt.Sprintf("agent/%d", i)
+		at := day.Add(time.Duration(i) * 24 * time.Hour)
+
+		sut.record("4611ce1", newClickRequest("4611ce1", "", userAgent), at)
+	}
+
+	got := sut.snapshot("4611ce1")
+
+	if len(got.ClicksPerDay) != maxDailyClicks {
+		t.Errorf("Incorrect number of days, got: %v, want: %v.", len(got.ClicksPerDay), maxDailyClicks)
+	}
+
+	if len(got.TopUserAgents) != topSources {
+		t.Errorf("Incorrect number of top user agents, got: %v, want: %v.", len(got.TopUserAgents), topSources)
+	}
+
+	if got.TopUserAgents[0] != (countJSON{otherSource, 10}) {
+		t.Errorf("Incorrect top user agent, got: %v, want: %v.", got.TopUserAgents[0], countJSON{otherSource, 10})
+	}
+}
+
+func TestLinkAnalyticsConcurrentRecord(t *testing.T) {
+	var sut linkAnalytics
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				sut.record("4611ce1", newClickRequest("4611ce1", "", ""), time.Now())
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if got := sut.snapshot("4611ce1"); got.Clicks != 800 {
+		t.Errorf("Incorrect clicks, got: %v, want: %v.", got.Clicks, 800)
+	}
+}
+
+func TestLinkStatisticsHandler(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	for i := 0; i < 3; i++ {
+		sut.expanderHandler(httptest.NewRecorder(), newClickRequest("4611ce1", "https://github.com/", "curl/7.68.0"))
+	}
+
+	request := httptest.NewRequest("GET", "/statistics/4611ce1", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.statisticsHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
+
+	if body := responseRecorder.Body.String(); !strings.Contains(body, "Total clicks: 3\n") {
+		t.Errorf("Missing total clicks in body: %s.", body)
+	}
+
+	request = httptest.NewRequest("GET", "/statistics/4611ce1?format=json", nil)
+	responseRecorder = httptest.NewRecorder()
+
+	sut.statisticsHandler(responseRecorder, request)
+
+	var stats linkStatsJSON
+	if err := json.Unmarshal(responseRecorder.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if stats.Clicks != 3 || stats.Code != "4611ce1" {
+		t.Errorf("Incorrect statistics, got: %v.", stats)
+	}
+
+	if len(stats.TopReferrers) != 1 || stats.TopReferrers[0] != (countJSON{"github.com", 3}) {
+		t.Errorf("Incorrect top referrers, got: %v.", stats.TopReferrers)
+	}
+
+	request = httptest.NewRequest("GET", "/statistics/1234567", nil)
+	responseRecorder = httptest.NewRecorder()
+
+	sut.statisticsHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestLinkStatisticsHandlerOwnership(t *testing.T) {
+	keys, _ := LoadAPIKeys(strings.NewReader(testAPIKeys))
+	sut := NewURLShortener(WithAPIKeys(keys))
+	sut.putLink("4611ce1", Link{URL: "https://github.com/develersrl/powersoft-hmi", Owner: "alice"})
+
+	sut.expanderHandler(httptest.NewRecorder(), newClickRequest("4611ce1", "https://github.com/", "curl/7.68.0"))
+
+	tests := []struct {
+		key            string
+		wantStatusCode int
+	}{
+		{"4l1c3-s3cr3t", http.StatusOK},
+		{"b0b-s3cr3t", http.StatusNotFound},
+		{"", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, newAuthenticatedRequest("GET", "/statistics/4611ce1?format=json", test.key, ""))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code with key %q, got: %v, want: %v.", test.key, responseRecorder.Code, test.wantStatusCode)
+		}
+
+		if test.wantStatusCode != http.StatusOK && strings.Contains(responseRecorder.Body.String(), "github.com") {
+			t.Errorf("Unexpected analytics with key %q in body: %s.", test.key, responseRecorder.Body.String())
+		}
+	}
+}
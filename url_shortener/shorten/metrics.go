@@ -0,0 +1,253 @@
+package shorten
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// redirectBuckets are the upper bounds in seconds of the redirect latency
+// histogram, the Prometheus client defaults
+var redirectBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram a lock free latency histogram with fixed buckets
+type histogram struct {
+	sumNs   int64
+	count   int64
+	bounds  []float64
+	buckets []int64 // not cumulative, the last one is +Inf
+}
+
+func newHistogram(bounds []float64) *histogram {
+	h := histogram{}
+
+	h.bounds = bounds
+	h.buckets = make([]int64, len(bounds)+1)
+
+	return &h
+}
+
+func (h *histogram) observe(duration time.Duration) {
+	seconds := duration.Seconds()
+	i := sort.SearchFloat64s(h.bounds, seconds)
+
+	atomic.AddInt64(&h.buckets[i], 1)
+	atomic.AddInt64(&h.sumNs, int64(duration))
+	atomic.AddInt64(&h.count, 1)
+}
+
+// statusCounters counts responses by status code
+type statusCounters struct {
+	mux    sync.Mutex
+	counts map[int]int64
+}
+
+func newStatusCounters() *statusCounters {
+	counters := statusCounters{}
+
+	counters.counts = make(map[int]int64)
+
+	return &counters
+}
+
+func (sc *statusCounters) increment(status int) {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	sc.counts[status]++
+}
+
+// sorted returns the status codes seen in ascending order with their counts
+func (sc *statusCounters) sorted() ([]int, map[int]int64) {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+
+	statuses := make([]int, 0, len(sc.counts))
+	counts := make(map[int]int64, len(sc.counts))
+
+	for status, count := range sc.counts {
+		statuses = append(statuses, status)
+		counts[status] = count
+	}
+
+	sort.Ints(statuses)
+
+	return statuses, counts
+}
+
+// statusRecorder captures the status code and the body size written by a
+// handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+
+	n, err := sr.ResponseWriter.Write(p)
+	sr.bytes += int64(n)
+
+	return n, err
+}
+
+// instrument wraps a handler accounting its responses by status code and,
+// for the expander, the redirect latency
+func (c *URLShortener) instrument(handlerIndex HandlerIndex, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		handler(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		c.statistics.observeResponse(handlerIndex, recorder.status, time.Since(start))
+	}
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	return replacer.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, +1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricsWriter writes metrics in the Prometheus text exposition format
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (mw metricsWriter) header(name, kind, help string) {
+	fmt.Fprintf(mw.w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(mw.w, "# TYPE %s %s\n", name, kind)
+}
+
+func (mw metricsWriter) sample(name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+
+	fmt.Fprintf(mw.w, "%s%s %s\n", name, labels, formatFloat(value))
+}
+
+// WriteMetrics writes the statistics in the Prometheus text exposition format
+func (s *StatsJSON) WriteMetrics(w io.Writer) {
+	mw := metricsWriter{w}
+	stats := &s.ServerStats
+
+	mw.header("shortener_urls", "gauge", "Number of short URL mappings.")
+	mw.sample("shortener_urls", "", float64(atomic.LoadInt64(&stats.TotalURL)))
+
+	mw.header("shortener_handler_requests_total", "counter", "Requests served per handler.")
+	for i := range stats.Handlers {
+		handler := &stats.Handlers[i]
+		labels := fmt.Sprintf(`handler="%s"`, escapeLabel(handler.Name))
+		mw.sample("shortener_handler_requests_total", labels, float64(atomic.LoadInt64(&handler.Count)))
+	}
+
+	mw.header("shortener_http_responses_total", "counter", "Responses per handler and status code.")
+	for i := range stats.Handlers {
+		handler := &stats.Handlers[i]
+		statuses, counts := handler.statuses.sorted()
+		for _, status := range statuses {
+			labels := fmt.Sprintf(`handler="%s",code="%d"`, escapeLabel(handler.Name), status)
+			mw.sample("shortener_http_responses_total", labels, float64(counts[status]))
+		}
+	}
+
+	mw.header("shortener_handler_duration_seconds", "summary", "Time spent serving requests per handler.")
+	for i := range stats.Handlers {
+		handler := &stats.Handlers[i]
+		labels := fmt.Sprintf(`handler="%s"`, escapeLabel(handler.Name))
+		mw.sample("shortener_handler_duration_seconds_sum", labels, time.Duration(atomic.LoadInt64(&handler.Latency.TotalNs)).Seconds())
+		mw.sample("shortener_handler_duration_seconds_count", labels, float64(atomic.LoadInt64(&handler.Count)))
+	}
+
+	mw.header("shortener_redirects_total", "counter", "Redirects by result.")
+	mw.sample("shortener_redirects_total", `result="success"`, float64(atomic.LoadInt64(&stats.Redirects.Success)))
+	mw.sample("shortener_redirects_total", `result="failed"`, float64(atomic.LoadInt64(&stats.Redirects.Failed)))
+
+	rateLimited := &stats.RateLimited
+	mw.header("shortener_rate_limited_total", "counter", "Requests refused by the rate limits.")
+	mw.sample("shortener_rate_limited_total", `kind="create"`, float64(atomic.LoadInt64(&rateLimited.Create)))
+	mw.sample("shortener_rate_limited_total", `kind="redirect"`, float64(atomic.LoadInt64(&rateLimited.Redirect)))
+
+	mw.header("shortener_rate_limit_rate", "gauge", "Requests per second allowed per client by the rate limits enabled.")
+	if limit := rateLimited.CreateLimit; limit != nil {
+		mw.sample("shortener_rate_limit_rate", `kind="create"`, limit.Rate)
+	}
+	if limit := rateLimited.RedirectLimit; limit != nil {
+		mw.sample("shortener_rate_limit_rate", `kind="redirect"`, limit.Rate)
+	}
+
+	mw.header("shortener_rate_limit_burst", "gauge", "Requests allowed per client in a burst by the rate limits enabled.")
+	if limit := rateLimited.CreateLimit; limit != nil {
+		mw.sample("shortener_rate_limit_burst", `kind="create"`, float64(limit.Burst))
+	}
+	if limit := rateLimited.RedirectLimit; limit != nil {
+		mw.sample("shortener_rate_limit_burst", `kind="redirect"`, float64(limit.Burst))
+	}
+
+	latency := s.redirectLatency
+	mw.header("shortener_redirect_duration_seconds", "histogram", "Latency of the redirects served.")
+	cumulative := int64(0)
+	for i := range latency.buckets {
+		bound := math.Inf(+1)
+		if i < len(latency.bounds) {
+			bound = latency.bounds[i]
+		}
+
+		cumulative += atomic.LoadInt64(&latency.buckets[i])
+		labels := fmt.Sprintf(`le="%s"`, formatFloat(bound))
+		mw.sample("shortener_redirect_duration_seconds_bucket", labels, float64(cumulative))
+	}
+	mw.sample("shortener_redirect_duration_seconds_sum", "", time.Duration(atomic.LoadInt64(&latency.sumNs)).Seconds())
+	mw.sample("shortener_redirect_duration_seconds_count", "", float64(atomic.LoadInt64(&latency.count)))
+
+	snapshots := &stats.Snapshots
+	mw.header("shortener_snapshots_total", "counter", "Snapshots of the mappings taken.")
+	mw.sample("shortener_snapshots_total", "", float64(atomic.LoadInt64(&snapshots.Count)))
+	mw.header("shortener_snapshot_failures_total", "counter", "Snapshots of the mappings failed.")
+	mw.sample("shortener_snapshot_failures_total", "", float64(atomic.LoadInt64(&snapshots.Failed)))
+	mw.header("shortener_snapshot_last_duration_seconds", "gauge", "Duration of the last snapshot.")
+	mw.sample("shortener_snapshot_last_duration_seconds", "", time.Duration(atomic.LoadInt64(&snapshots.LastDurationNs)).Seconds())
+	mw.header("shortener_snapshot_last_size_bytes", "gauge", "Size of the last snapshot.")
+	mw.sample("shortener_snapshot_last_size_bytes", "", float64(atomic.LoadInt64(&snapshots.LastSizeBytes)))
+	mw.header("shortener_snapshot_last_timestamp_seconds", "gauge", "Unix time of the last snapshot.")
+	mw.sample("shortener_snapshot_last_timestamp_seconds", "", float64(atomic.LoadInt64(&snapshots.LastUnixTime)))
+}
+
+func (c *URLShortener) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	c.statistics.WriteMetrics(w)
+}
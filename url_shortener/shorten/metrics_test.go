@@ -0,0 +1,121 @@
+package shorten
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	sut := newHistogram([]float64{0.1, 1})
+
+	durations := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second}
+	for _, duration := range durations {
+		sut.observe(duration)
+	}
+
+	wantBuckets := []int64{2, 1, 1}
+	for i, want := range wantBuckets {
+		if sut.buckets[i] != want {
+			t.Errorf("Incorrect bucket %d count, got: %v, want: %v.", i, sut.buckets[i], want)
+		}
+	}
+
+	if sut.count != 4 {
+		t.Errorf("Incorrect count, got: %v, want: %v.", sut.count, 4)
+	}
+
+	if want := int64(2650 * time.Millisecond); sut.sumNs != want {
+		t.Errorf("Incorrect sum, got: %v, want: %v.", sut.sumNs, want)
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	expander := sut.instrument(ExpanderHandlerIndex, sut.expanderHandler)
+
+	paths := []string{"/4611ce1", "/4611ce1", "/1234567"}
+	for _, path := range paths {
+		expander(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	handler := sut.statistics.ServerStats.Handlers[ExpanderHandlerIndex]
+	statuses, counts := handler.statuses.sorted()
+
+	if len(statuses) != 2 || counts[http.StatusSeeOther] != 2 || counts[http.StatusNotFound] != 1 {
+		t.Errorf("Incorrect status counts, got: %v, want: %v.", counts, map[int]int64{http.StatusSeeOther: 2, http.StatusNotFound: 1})
+	}
+
+	if sut.statistics.redirectLatency.count != 3 {
+		t.Errorf("Incorrect redirect latency count, got: %v, want: %v.", sut.statistics.redirectLatency.count, 3)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	expander := sut.instrument(ExpanderHandlerIndex, sut.expanderHandler)
+	expander(httptest.NewRecorder(), httptest.NewRequest("GET", "/4611ce1", nil))
+
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.metricsHandler(responseRecorder, request)
+
+	if contentType := responseRecorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Incorrect content type, got: %s, want: %s.", contentType, "text/plain; version=0.0.4")
+	}
+
+	body := responseRecorder.Body.String()
+
+	wantLines := []string{
+		"# TYPE shortener_urls gauge",
+		"shortener_urls 1",
+		`shortener_handler_requests_total{handler="/"} 1`,
+		`shortener_http_responses_total{handler="/",code="303"} 1`,
+		`shortener_redirect_duration_seconds_bucket{le="+Inf"} 1`,
+		"shortener_redirect_duration_seconds_count 1",
+		"shortener_snapshots_total 0",
+	}
+
+	for _, line := range wantLines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Missing line %q in body: %s.", line, body)
+		}
+	}
+
+	sample := regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*(\{([a-zA-Z_][a-zA-Z0-9_]*="([^"\\]|\\.)*",?)*\})? (\+Inf|[-+0-9.eE]+)$`)
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "# HELP ") || strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+
+		if !sample.MatchString(line) {
+			t.Errorf("Incorrect sample line: %s.", line)
+		}
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantValue string
+	}{
+		{"/shorten/", "/shorten/"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\dir`, `C:\\dir`},
+		{"two\nlines", `two\nlines`},
+	}
+
+	for _, test := range tests {
+		if got := escapeLabel(test.value); got != test.wantValue {
+			t.Errorf("Incorrect escaped label, got: %s, want: %s.", got, test.wantValue)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package shorten
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// RequestIDHeader the header carrying the identifier of a request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDSize bounds the request identifiers accepted from clients
+const maxRequestIDSize = 128
+
+type requestIDKey struct{}
+
+// Middleware wraps an http.Handler adding a behavior
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler with the middlewares, the first one is the outermost
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// RequestID assigns every request an identifier, the one received in the
+// X-Request-ID header when valid or a random one. The identifier is echoed in
+// the response and is available to inner handlers through RequestIDFrom.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+
+			if !isValidRequestID(id) {
+				id = newRequestID()
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFrom returns the identifier the RequestID middleware assigned to
+// the request of ctx, empty when none was
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+
+	return id
+}
+
+// isValidRequestID tells if a client provided identifier is short and made
+// of printable ASCII characters, so it is safe to log
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDSize {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' || id[i] == '"' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// AccessLog logs a line for every request served as key=value pairs: the
+// request identifier, method, path, status, bytes written, duration and
+// remote address
+func AccessLog(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			next.ServeHTTP(recorder, r)
+
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+
+			logger.Printf("request_id=%s method=%s path=%s status=%d bytes=%d duration=%s remote_addr=%s",
+				logValue(RequestIDFrom(r.Context())), logValue(r.Method), logValue(r.URL.Path),
+				recorder.status, recorder.bytes, time.Since(start), logValue(r.RemoteAddr))
+		})
+	}
+}
+
+// logValue quotes a value when needed so each log line stays parseable
+func logValue(value string) string {
+	if value == "" {
+		return `""`
+	}
+
+	for i := 0; i < len(value); i++ {
+		if value[i] <= ' ' || value[i] > '~' || value[i] == '"' || value[i] == '=' {
+			return strconv.Quote(value)
+		}
+	}
+
+	return value
+}
+
+// Recover turns a panic of a handler into a 500 response, logging the panic
+// with its stack trace. http.ErrAbortHandler is left to the server.
+func Recover(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			recorder := &statusRecorder{ResponseWriter: w}
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				logger.Printf("request_id=%s panic=%s\n%s",
+					logValue(RequestIDFrom(r.Context())), logValue(fmt.Sprint(recovered)), debug.Stack())
+
+				if recorder.status == 0 {
+					http.Error(recorder, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package shorten
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	order := make([]string, 0)
+
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := Chain(http.NotFoundHandler(), tag("outer"), tag("inner"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("Incorrect middleware order, got: %v, want: %v.", order, []string{"outer", "inner"})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var gotID string
+
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = RequestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		header   string
+		wantKept bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"with space", false},
+		{strings.Repeat("a", maxRequestIDSize+1), false},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/", nil)
+		if test.header != "" {
+			request.Header.Set(RequestIDHeader, test.header)
+		}
+		responseRecorder := httptest.NewRecorder()
+
+		handler.ServeHTTP(responseRecorder, request)
+
+		responseID := responseRecorder.Header().Get(RequestIDHeader)
+
+		if responseID == "" || responseID != gotID {
+			t.Errorf("Incorrect request ID, got: %s in response and %s in context.", responseID, gotID)
+		}
+
+		if kept := responseID == test.header; kept != test.wantKept {
+			t.Errorf("Incorrect request ID for header %q, got: %s.", test.header, responseID)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := log.New(logs, "", 0)
+
+	sut := NewURLShortener()
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	handler := Chain(sut, RequestID(), AccessLog(logger))
+
+	request := httptest.NewRequest("GET", "/f495791", nil)
+	request.Header.Set(RequestIDHeader, "abc-123")
+	request.RemoteAddr = "192.0.2.1:1234"
+
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	line := logs.String()
+	wantFields := []string{"request_id=abc-123 ", "method=GET ", "path=/f495791 ", "status=303 ", "bytes=", "duration=", "remote_addr=192.0.2.1:1234\n"}
+
+	for _, field := range wantFields {
+		if !strings.Contains(line, field) {
+			t.Errorf("Missing field %q in log line: %s.", field, line)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logs := &bytes.Buffer{}
+	logger := log.New(logs, "", 0)
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	handler := Chain(panicking, RequestID(), AccessLog(logger), Recover(logger))
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(RequestIDHeader, "abc-123")
+	responseRecorder := httptest.NewRecorder()
+
+	handler.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusInternalServerError {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusInternalServerError)
+	}
+
+	output := logs.String()
+
+	if !strings.Contains(output, "request_id=abc-123 panic=boom") {
+		t.Errorf("Missing panic in logs: %s.", output)
+	}
+
+	if !strings.Contains(output, "status=500") {
+		t.Errorf("Missing status in logs: %s.", output)
+	}
+}
+
+func TestLogValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantValue string
+	}{
+		{"/f495791", "/f495791"},
+		{"", `""`},
+		{"/a b", `"/a b"`},
+		{"k=v", `"k=v"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+
+	for _, test := range tests {
+		if got := logValue(test.value); got != test.wantValue {
+			t.Errorf("Incorrect log value, got: %s, want: %s.", got, test.wantValue)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package shorten
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Media types the shorten route can answer with
+const (
+	mediaTypeHTML = "text/html"
+	mediaTypeJSON = "application/json"
+	mediaTypeText = "text/plain"
+)
+
+// negotiate returns the offer the Accept header prefers, offers are media
+// types listed by server preference. It returns the empty string when the
+// header is missing, accepts anything without preference, as in */*, or
+// accepts none of the offers.
+func negotiate(accept string, offers ...string) string {
+	best, bestQuality, explicit := "", 0.0, false
+
+	for _, offer := range offers {
+		quality, specific := acceptQuality(accept, offer)
+
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+
+		explicit = explicit || (specific && quality > 0)
+	}
+
+	if !explicit {
+		return ""
+	}
+
+	return best
+}
+
+// acceptQuality returns the quality the Accept header gives to a media type,
+// from its most specific matching range, and if that range is more specific
+// than */*
+func acceptQuality(accept, mediaType string) (float64, bool) {
+	quality, specificity := 0.0, -1
+
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		rangeSpecificity := matchMediaRange(rangeType, mediaType)
+		if rangeSpecificity <= specificity {
+			continue
+		}
+
+		specificity = rangeSpecificity
+		quality = 1
+
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				quality = parsed
+			}
+		}
+	}
+
+	return quality, specificity > 0
+}
+
+// matchMediaRange returns how specific a media range matching the media type
+// is: 2 for the type itself, 1 for type/*, 0 for */*, -1 when not matching
+func matchMediaRange(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+
+	return -1
+}
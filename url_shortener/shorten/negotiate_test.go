@@ -0,0 +1,31 @@
+package shorten
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{mediaTypeHTML, mediaTypeJSON, mediaTypeText}
+
+	tests := []struct {
+		accept    string
+		wantOffer string
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"image/png", ""},
+		{"application/json", mediaTypeJSON},
+		{"text/plain", mediaTypeText},
+		{"text/*", mediaTypeHTML},
+		{"text/*, text/html;q=0", mediaTypeText},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", mediaTypeHTML},
+		{"application/json;q=0.5, text/plain", mediaTypeText},
+		{"application/json, */*;q=0.1", mediaTypeJSON},
+		{"TEXT/PLAIN", mediaTypeText},
+		{"application/json;q=0", ""},
+	}
+
+	for _, test := range tests {
+		if got := negotiate(test.accept, offers...); got != test.wantOffer {
+			t.Errorf("Incorrect offer for %q, got: %q, want: %q.", test.accept, got, test.wantOffer)
+		}
+	}
+}
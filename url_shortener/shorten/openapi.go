@@ -0,0 +1,186 @@
+package shorten
+
+// openAPIDocument describes the links API, {{links}} and {{bulk}} are
+// replaced with the routes of the links resource and of the bulk imports
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "URL shortener links API",
+    "version": "1.0.0"
+  },
+  "security": [{"apiKey": []}],
+  "paths": {
+    "{{links}}": {
+      "get": {
+        "summary": "List links not expired sorted by code",
+        "parameters": [
+          {"name": "offset", "in": "query", "schema": {"type": "integer", "minimum": 0, "default": 0}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 1000, "default": 50}}
+        ],
+        "responses": {
+          "200": {"description": "A page of links", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/LinksPage"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Create a link",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateLink"}}}
+        },
+        "responses": {
+          "200": {"description": "The existing link already holding the URL, or the alias renewed", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Link"}}}},
+          "201": {"description": "The link created", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Link"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "429": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete every link of the API key, available only when API keys are required",
+        "responses": {
+          "200": {"description": "The links deleted", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Revoked"}}}},
+          "401": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "{{links}}/{code}": {
+      "parameters": [
+        {"name": "code", "in": "path", "required": true, "schema": {"type": "string"}}
+      ],
+      "get": {
+        "summary": "Get a link",
+        "responses": {
+          "200": {"description": "The link", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Link"}}}},
+          "401": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "410": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a link",
+        "responses": {
+          "204": {"description": "The link was deleted"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "{{bulk}}": {
+      "post": {
+        "summary": "Import links in bulk, reporting the rows not imported",
+        "parameters": [
+          {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv", "jsonl", "json"]}, "description": "Format of the body, by default from its media type"}
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "text/csv": {"schema": {"type": "string", "description": "A header row naming the columns code, url, created_at, expires_at, owner, redirect_code and interstitial, only url is required"}},
+            "application/x-ndjson": {"schema": {"$ref": "#/components/schemas/BulkLink"}},
+            "application/json": {"schema": {"type": "object", "additionalProperties": {"$ref": "#/components/schemas/BulkLink"}, "description": "The persistence file, links by code"}}
+          }
+        },
+        "responses": {
+          "200": {"description": "The import report", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/ImportReport"}}}},
+          "400": {"$ref": "#/components/responses/Error"},
+          "401": {"$ref": "#/components/responses/Error"},
+          "413": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"},
+          "429": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "CreateLink": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "url": {"type": "string", "maxLength": 8192},
+          "alias": {"type": "string", "pattern": "^[0-9A-Za-z_-]{3,64}$"},
+          "ttl": {"type": "string", "description": "Time to live as a Go duration, as in 72h"},
+          "expires_at": {"type": "string", "format": "date-time"},
+          "redirect_code": {"type": "integer", "enum": [301, 302, 303, 307, 308], "description": "Status code of the redirects, the server default when missing"},
+          "interstitial": {"type": "boolean", "description": "Show a page naming the destination instead of redirecting"}
+        }
+      },
+      "Link": {
+        "type": "object",
+        "properties": {
+          "code": {"type": "string"},
+          "short_url": {"type": "string"},
+          "long_url": {"type": "string"},
+          "created_at": {"type": "string", "format": "date-time"},
+          "expires_at": {"type": "string", "format": "date-time"},
+          "owner": {"type": "string", "description": "Name of the API key that created the link"},
+          "redirect_code": {"type": "integer"},
+          "interstitial": {"type": "boolean"}
+        }
+      },
+      "LinksPage": {
+        "type": "object",
+        "properties": {
+          "links": {"type": "array", "items": {"$ref": "#/components/schemas/Link"}},
+          "total": {"type": "integer"},
+          "offset": {"type": "integer"},
+          "limit": {"type": "integer"}
+        }
+      },
+      "BulkLink": {
+        "type": "object",
+        "required": ["url"],
+        "properties": {
+          "code": {"type": "string", "description": "Generated when missing"},
+          "url": {"type": "string", "maxLength": 8192},
+          "created_at": {"type": "string", "format": "date-time"},
+          "expires_at": {"type": "string", "format": "date-time"},
+          "owner": {"type": "string", "description": "Replaced with the name of the API key when API keys are required"},
+          "redirect_code": {"type": "integer", "enum": [301, 302, 303, 307, 308]},
+          "interstitial": {"type": "boolean"}
+        }
+      },
+      "BulkIssue": {
+        "type": "object",
+        "properties": {
+          "row": {"type": "integer"},
+          "code": {"type": "string"},
+          "error": {"type": "string"}
+        }
+      },
+      "ImportReport": {
+        "type": "object",
+        "properties": {
+          "imported": {"type": "integer"},
+          "unchanged": {"type": "integer"},
+          "conflicts": {"type": "array", "items": {"$ref": "#/components/schemas/BulkIssue"}},
+          "invalid": {"type": "array", "items": {"$ref": "#/components/schemas/BulkIssue"}}
+        }
+      },
+      "Revoked": {
+        "type": "object",
+        "properties": {
+          "deleted": {"type": "integer"}
+        }
+      },
+      "Error": {
+        "type": "object",
+        "properties": {
+          "error": {"type": "string"}
+        }
+      }
+    },
+    "securitySchemes": {
+      "apiKey": {"type": "http", "scheme": "bearer", "description": "Required only when the server is configured with API keys"}
+    },
+    "responses": {
+      "Error": {
+        "description": "An error",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,167 @@
+package shorten
+
+import (
+	"net"
+	"net/url"
+)
+
+// Option configures a URLShortener at construction time
+type Option func(*URLShortener)
+
+// WithStore sets the storage backend used for the URL mappings
+func WithStore(store Store) Option {
+	return func(c *URLShortener) {
+		c.store = store
+	}
+}
+
+// WithWAL sets a write-ahead log where every new mapping is durably appended
+// before being stored
+func WithWAL(wal *WAL) Option {
+	return func(c *URLShortener) {
+		c.wal = wal
+	}
+}
+
+// WithCodeGenerator sets the generator of short URLs, a HashGenerator by
+// default
+func WithCodeGenerator(generator CodeGenerator) Option {
+	return func(c *URLShortener) {
+		c.generator = generator
+	}
+}
+
+// WithAllowedSchemes sets the schemes long URLs may use, http and https by
+// default
+func WithAllowedSchemes(schemes ...string) Option {
+	return func(c *URLShortener) {
+		c.allowedSchemes = schemes
+	}
+}
+
+// WithSelfHosts sets further hosts the shortener is reachable at, long URLs
+// pointing at them are refused so links cannot loop
+func WithSelfHosts(hosts ...string) Option {
+	return func(c *URLShortener) {
+		c.selfHosts = hosts
+	}
+}
+
+// WithSortedQuery enables sorting the query parameters of long URLs by key
+// when normalizing them
+func WithSortedQuery() Option {
+	return func(c *URLShortener) {
+		c.sortQuery = true
+	}
+}
+
+// WithRoutePrefix mounts every route under prefix, as in /s/, so the
+// shortener can be served along with other handlers
+func WithRoutePrefix(prefix string) Option {
+	return func(c *URLShortener) {
+		c.routePrefix = prefix
+	}
+}
+
+// WithExpanderRoute sets the route redirecting short URLs, / by default. A
+// trailing slash is added when missing.
+func WithExpanderRoute(route string) Option {
+	return func(c *URLShortener) {
+		c.expanderRoute = route
+	}
+}
+
+// WithShortenRoute sets the route creating short URLs, /shorten by default
+func WithShortenRoute(route string) Option {
+	return func(c *URLShortener) {
+		c.shortenRoute = route
+	}
+}
+
+// WithStatisticsRoute sets the route of the statistics, /statistics by
+// default
+func WithStatisticsRoute(route string) Option {
+	return func(c *URLShortener) {
+		c.statisticsRoute = route
+	}
+}
+
+// WithCreateRateLimit limits per client the creation of short URLs, on
+// /shorten and on the API. Limits not passing Validate are ignored.
+func WithCreateRateLimit(limit RateLimit) Option {
+	return func(c *URLShortener) {
+		if limit.Validate() == nil {
+			c.createLimiter = newRateLimiter(createLimit, limit)
+		}
+	}
+}
+
+// WithRedirectRateLimit limits per client the redirects of short URLs.
+// Limits not passing Validate are ignored.
+func WithRedirectRateLimit(limit RateLimit) Option {
+	return func(c *URLShortener) {
+		if limit.Validate() == nil {
+			c.redirectLimiter = newRateLimiter(redirectLimit, limit)
+		}
+	}
+}
+
+// WithTrustedProxies sets the proxies whose X-Forwarded-For header is trusted
+// to identify rate limited clients
+func WithTrustedProxies(proxies ...*net.IPNet) Option {
+	return func(c *URLShortener) {
+		c.trustedProxies = proxies
+	}
+}
+
+// WithAPIKeys requires one of the keys as bearer token on every route but the
+// redirects, links record the name of the key that created them
+func WithAPIKeys(keys *APIKeys) Option {
+	return func(c *URLShortener) {
+		c.apiKeys = keys
+	}
+}
+
+// WithAdminToken requires the token as bearer token on every route of the
+// admin API
+func WithAdminToken(token string) Option {
+	return func(c *URLShortener) {
+		c.adminToken = token
+	}
+}
+
+// WithRedirectCode sets the status code of the redirects of links without
+// their own, 303 by default. Codes other than 301, 302, 303, 307 and 308 are
+// ignored.
+func WithRedirectCode(code int) Option {
+	return func(c *URLShortener) {
+		if IsRedirectCode(code) {
+			c.defaultRedirectCode = code
+		}
+	}
+}
+
+// WithQueryPassthrough appends the query string of the requests of short URLs
+// to the long URLs redirected to
+func WithQueryPassthrough() Option {
+	return func(c *URLShortener) {
+		c.passQuery = true
+	}
+}
+
+// WithPublicURL sets the base URL the shortener is publicly reachable at, the
+// short links returned are built on it instead of on the request host
+func WithPublicURL(publicURL *url.URL) Option {
+	return func(c *URLShortener) {
+		c.publicURL = publicURL
+	}
+}
+
+// WithTrustedForwardedHeaders builds the short links returned on the
+// X-Forwarded-Host and X-Forwarded-Proto headers, only from the trusted
+// proxies when set. The public URL takes precedence.
+func WithTrustedForwardedHeaders() Option {
+	return func(c *URLShortener) {
+		c.trustForwarded = true
+	}
+}
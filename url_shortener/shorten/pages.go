@@ -0,0 +1,81 @@
+package shorten
+
+import "html/template"
+
+// copyScript copies the short URL of the shortened page, the only script
+// the Content-Security-Policy allows
+const copyScript = `
+document.getElementById("copy").addEventListener("click", function () {
+  navigator.clipboard.writeText(document.getElementById("short-url").href).then(function () {
+    document.getElementById("copy").textContent = "Copied";
+  });
+});
+`
+
+// shortenedFragment the bare link answering /shorten to clients without
+// preference, as the first versions did
+var shortenedFragment = template.Must(template.New("fragment").Parse(`<a href="{{.ShortURL}}">{{.Code}} -> {{.LongURL}}</a>`))
+
+// shortenedPage the page answering /shorten to browsers
+var shortenedPage = template.Must(template.New("shortened").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Short URL {{.Code}}</title>
+</head>
+<body>
+<h1>Your short URL</h1>
+<p><a id="short-url" href="{{.ShortURL}}">{{.ShortURL}}</a>
+<button id="copy" type="button">Copy</button></p>
+<p>Redirects to <a href="{{.LongURL}}" rel="noopener noreferrer">{{.LongURL}}</a></p>
+<script>` + copyScript + `</script>
+</body>
+</html>
+`))
+
+// previewPage the page describing a link without following it
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Preview of {{.Code}}</title>
+</head>
+<body>
+<h1>Preview of {{.ShortURL}}</h1>
+<dl>
+<dt>Destination</dt>
+<dd><a href="{{.LongURL}}" rel="noopener noreferrer">{{.LongURL}}</a></dd>
+<dt>Created</dt>
+<dd>{{if .CreatedAt.IsZero}}unknown{{else}}<time datetime="{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.Format "2006-01-02 15:04 MST"}}</time>{{end}}</dd>
+{{- if not .ExpiresAt.IsZero}}
+<dt>Expires</dt>
+<dd><time datetime="{{.ExpiresAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.ExpiresAt.Format "2006-01-02 15:04 MST"}}</time></dd>
+{{- end}}
+<dt>Clicks</dt>
+<dd>{{.Clicks}}</dd>
+</dl>
+</body>
+</html>
+`))
+
+// interstitialPage the page shown instead of redirecting to the destination
+// of interstitial links
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Leaving to {{.LongURL}}</title>
+</head>
+<body>
+<h1>You are leaving to</h1>
+<p><code>{{.LongURL}}</code></p>
+<p>The short URL {{.ShortURL}} leads to the address above, continue only if you trust it.</p>
+<p><a id="continue" href="{{.Destination}}" rel="noopener noreferrer" role="button">Continue</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,87 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// previewSuffix follows a code to preview its link instead of following it
+const previewSuffix = "+"
+
+// ErrInvalidInterstitial is returned for interstitial flags not boolean
+var ErrInvalidInterstitial = errors.New("invalid interstitial flag")
+
+// linkPage the data of the pages about a link
+type linkPage struct {
+	Code        string
+	ShortURL    string
+	LongURL     string
+	Destination string
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	Clicks      int64
+}
+
+// parseInterstitial parses the interstitial flag requested for a link, empty
+// means false
+func parseInterstitial(raw string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+
+	interstitial, err := strconv.ParseBool(raw)
+
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidInterstitial, raw)
+	}
+
+	return interstitial, nil
+}
+
+func (c *URLShortener) newLinkPage(r *http.Request, shortURL string, link Link) linkPage {
+	page := linkPage{}
+
+	page.Code = shortURL
+	page.ShortURL = c.shortLink(r, shortURL)
+	page.LongURL = link.URL
+	page.Destination = c.destination(link, r)
+	page.CreatedAt = link.CreatedAt
+	page.ExpiresAt = link.ExpiresAt
+	page.Clicks = c.analytics.snapshot(shortURL).Clicks
+
+	return page
+}
+
+// previewHandler shows where a short URL leads, when it was created and how
+// many times it was followed, without redirecting
+func (c *URLShortener) previewHandler(w http.ResponseWriter, r *http.Request) {
+	shortURL := strings.TrimSuffix(r.URL.Path[len(c.expanderRoute):], previewSuffix)
+
+	link, err := c.GetLink(shortURL)
+
+	if errors.Is(err, ErrExpired) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	previewPage.Execute(w, c.newLinkPage(r, shortURL, link))
+}
+
+// renderInterstitial answers a redirect of an interstitial link with a page
+// naming the destination, followed only when the user continues
+func (c *URLShortener) renderInterstitial(w http.ResponseWriter, r *http.Request, shortURL string, link Link) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	interstitialPage.Execute(w, c.newLinkPage(r, shortURL, link))
+}
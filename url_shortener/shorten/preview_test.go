@@ -0,0 +1,140 @@
+package shorten
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInterstitial(t *testing.T) {
+	tests := []struct {
+		value            string
+		wantInterstitial bool
+		wantError        error
+	}{
+		{"", false, nil},
+		{"1", true, nil},
+		{"true", true, nil},
+		{"false", false, nil},
+		{"maybe", false, ErrInvalidInterstitial},
+	}
+
+	for _, test := range tests {
+		got, err := parseInterstitial(test.value)
+
+		if !errors.Is(err, test.wantError) {
+			t.Errorf("Incorrect error for %q, got: %v, want: %v.", test.value, err, test.wantError)
+		}
+
+		if got != test.wantInterstitial {
+			t.Errorf("Incorrect interstitial for %q, got: %v, want: %v.", test.value, got, test.wantInterstitial)
+		}
+	}
+}
+
+func TestPreviewHandler(t *testing.T) {
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return now
+	}
+
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+	sut.putLink("expired", Link{URL: "https://wttr.in/Rome", ExpiresAt: now.Add(-time.Hour)})
+
+	for i := 0; i < 2; i++ {
+		sut.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/4611ce1", nil))
+	}
+
+	request := httptest.NewRequest("GET", "/4611ce1+", nil)
+	request.Host = "localhost:9090"
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
+
+	if location := responseRecorder.Header().Get("Location"); location != "" {
+		t.Errorf("Unexpected redirect to: %s.", location)
+	}
+
+	body := responseRecorder.Body.String()
+	wantParts := []string{
+		`<a href="https://github.com/develersrl/powersoft-hmi" rel="noopener noreferrer">`,
+		"Preview of http://localhost:9090/4611ce1",
+		"2020-09-08 10:00 UTC",
+		"<dd>2</dd>",
+	}
+
+	for _, part := range wantParts {
+		if !strings.Contains(body, part) {
+			t.Errorf("Missing %q in body: %s.", part, body)
+		}
+	}
+
+	if clicks := sut.analytics.snapshot("4611ce1").Clicks; clicks != 2 {
+		t.Errorf("Incorrect clicks after preview, got: %v, want: %v.", clicks, 2)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/1234567+", http.StatusNotFound},
+		{"/expired+", http.StatusGone},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.path, nil))
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.path, responseRecorder.Code, test.wantStatus)
+		}
+	}
+}
+
+func TestInterstitial(t *testing.T) {
+	sut := NewURLShortener()
+
+	request := httptest.NewRequest("GET", "/shorten?url=https://wttr.in/Rome&alias=rome&interstitial=1", nil)
+	sut.ServeHTTP(httptest.NewRecorder(), request)
+
+	request = httptest.NewRequest("GET", "/rome?lang=it", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
+
+	if location := responseRecorder.Header().Get("Location"); location != "" {
+		t.Errorf("Unexpected redirect to: %s.", location)
+	}
+
+	body := responseRecorder.Body.String()
+	if !strings.Contains(body, `<a id="continue" href="https://wttr.in/Rome"`) {
+		t.Errorf("Missing continue link in body: %s.", body)
+	}
+
+	if clicks := sut.analytics.snapshot("rome").Clicks; clicks != 1 {
+		t.Errorf("Incorrect clicks, got: %v, want: %v.", clicks, 1)
+	}
+
+	request = httptest.NewRequest("GET", "/shorten?url=https://wttr.in/Rome&interstitial=maybe", nil)
+	responseRecorder = httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusBadRequest)
+	}
+}
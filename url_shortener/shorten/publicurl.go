@@ -0,0 +1,111 @@
+package shorten
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParsePublicURL parses the base URL the shortener is publicly reachable at,
+// as in https://sho.rt, an absolute http or https URL without query
+func ParsePublicURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public URL: %w", err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid public URL: want an absolute http or https URL: %s", raw)
+	}
+
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("invalid public URL: unexpected user, query or fragment: %s", raw)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+
+	return u, nil
+}
+
+// forwardedValue returns the first value of a X-Forwarded-* header, proxies
+// append theirs to the one of the client facing proxy
+func forwardedValue(r *http.Request, header string) string {
+	return strings.TrimSpace(strings.SplitN(r.Header.Get(header), ",", 2)[0])
+}
+
+// isValidForwardedHost tells if a forwarded host is a plain host, optionally
+// with a port, so it cannot alter the path of the links built with it
+func isValidForwardedHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\?#@ ") {
+		return false
+	}
+
+	u, err := url.Parse("http://" + host)
+
+	return err == nil && u.Host == host
+}
+
+// trustsForwarded tells if the X-Forwarded-Host and X-Forwarded-Proto headers
+// of r are trusted: they must be enabled and, when trusted proxies are set,
+// come from one of them
+func (c *URLShortener) trustsForwarded(r *http.Request) bool {
+	if !c.trustForwarded {
+		return false
+	}
+
+	if len(c.trustedProxies) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && c.isTrustedProxy(ip)
+}
+
+// requestHost returns the scheme and host the client of r used: the public
+// URL ones when configured, the forwarded ones when trusted, otherwise the
+// ones of r
+func (c *URLShortener) requestHost(r *http.Request) (string, string) {
+	if c.publicURL != nil {
+		return c.publicURL.Scheme, c.publicURL.Host
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if !c.trustsForwarded(r) {
+		return scheme, host
+	}
+
+	if proto := strings.ToLower(forwardedValue(r, "X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	if forwardedHost := forwardedValue(r, "X-Forwarded-Host"); isValidForwardedHost(forwardedHost) {
+		host = forwardedHost
+	}
+
+	return scheme, host
+}
+
+// shortLink returns the absolute short URL as seen by the client of r
+func (c *URLShortener) shortLink(r *http.Request, shortURL string) string {
+	scheme, host := c.requestHost(r)
+
+	base := scheme + "://" + host
+	if c.publicURL != nil {
+		base += c.publicURL.EscapedPath()
+	}
+
+	return base + c.expanderRoute + shortURL
+}
@@ -0,0 +1,106 @@
+package shorten
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePublicURL(t *testing.T) {
+	tests := []struct {
+		raw       string
+		wantValue string
+		wantErr   bool
+	}{
+		{"https://sho.rt", "https://sho.rt", false},
+		{"https://sho.rt/", "https://sho.rt", false},
+		{"http://sho.rt:8080/s/", "http://sho.rt:8080/s", false},
+		{"sho.rt", "", true},
+		{"ftp://sho.rt", "", true},
+		{"https://sho.rt/?a=b", "", true},
+		{"https://user@sho.rt", "", true},
+	}
+
+	for _, test := range tests {
+		u, err := ParsePublicURL(test.raw)
+
+		if test.wantErr != (err != nil) {
+			t.Errorf("Incorrect error for %s, got: %v, want error: %v.", test.raw, err, test.wantErr)
+			continue
+		}
+
+		if err == nil && u.String() != test.wantValue {
+			t.Errorf("Incorrect public URL, got: %s, want: %s.", u, test.wantValue)
+		}
+	}
+}
+
+func TestShortLinkHost(t *testing.T) {
+	publicURL, _ := ParsePublicURL("https://sho.rt/s")
+	proxies, _ := ParseTrustedProxies([]string{"10.0.0.1"})
+
+	tests := []struct {
+		options        []Option
+		remoteAddr     string
+		forwardedHost  string
+		forwardedProto string
+		wantLink       string
+	}{
+		{nil, "10.0.0.1:1234", "sho.rt", "https", "http://localhost:9090/f495791"},
+		{[]Option{WithPublicURL(publicURL)}, "10.0.0.1:1234", "evil.example", "http", "https://sho.rt/s/f495791"},
+		{[]Option{WithTrustedForwardedHeaders()}, "192.0.2.1:1234", "sho.rt", "https", "https://sho.rt/f495791"},
+		{[]Option{WithTrustedForwardedHeaders()}, "192.0.2.1:1234", "sho.rt/path", "gopher", "http://localhost:9090/f495791"},
+		{[]Option{WithTrustedForwardedHeaders(), WithTrustedProxies(proxies...)}, "192.0.2.1:1234", "evil.example", "https", "http://localhost:9090/f495791"},
+		{[]Option{WithTrustedForwardedHeaders(), WithTrustedProxies(proxies...)}, "10.0.0.1:1234", "sho.rt, proxy.internal", "https", "https://sho.rt/f495791"},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener(test.options...)
+
+		request := httptest.NewRequest("GET", "/shorten?url=https://wttr.in/Florence", nil)
+		request.Host = "localhost:9090"
+		request.RemoteAddr = test.remoteAddr
+		request.Header.Set("X-Forwarded-Host", test.forwardedHost)
+		request.Header.Set("X-Forwarded-Proto", test.forwardedProto)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, request)
+
+		if body := responseRecorder.Body.String(); !strings.Contains(body, `href="`+test.wantLink+`"`) {
+			t.Errorf("Incorrect short link, got: %s, want: %s.", body, test.wantLink)
+		}
+
+		request = httptest.NewRequest("GET", "/api/v1/links/f495791", nil)
+		request.Host = "localhost:9090"
+		request.RemoteAddr = test.remoteAddr
+		request.Header.Set("X-Forwarded-Host", test.forwardedHost)
+		request.Header.Set("X-Forwarded-Proto", test.forwardedProto)
+		responseRecorder = httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, request)
+
+		var link linkJSON
+		json.Unmarshal(responseRecorder.Body.Bytes(), &link)
+
+		if link.ShortURL != test.wantLink {
+			t.Errorf("Incorrect API short URL, got: %s, want: %s.", link.ShortURL, test.wantLink)
+		}
+	}
+}
+
+func TestPublicHostIsSelfHost(t *testing.T) {
+	publicURL, _ := ParsePublicURL("https://sho.rt")
+
+	sut := NewURLShortener(WithPublicURL(publicURL))
+
+	request := httptest.NewRequest("GET", "/shorten?url=https://sho.rt/f495791", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusBadRequest)
+	}
+}
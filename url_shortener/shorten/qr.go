@@ -0,0 +1,115 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rgianassi/learning/go/url_shortener/qrcode"
+)
+
+// QR code size bounds in pixels
+const (
+	defaultQRCodeSize = 256
+	maxQRCodeSize     = 2048
+)
+
+// defaultQRCodeLevel the error correction level used unless requested
+const defaultQRCodeLevel = qrcode.Medium
+
+// QR code image formats by code extension
+const (
+	qrCodePNG = ".png"
+	qrCodeSVG = ".svg"
+)
+
+// splitQRCodePath splits the code and the image extension of a QR code path
+func splitQRCodePath(path string) (string, string, bool) {
+	for _, extension := range []string{qrCodePNG, qrCodeSVG} {
+		if strings.HasSuffix(path, extension) {
+			return strings.TrimSuffix(path, extension), extension, true
+		}
+	}
+
+	return "", "", false
+}
+
+// parseQRCodeSize parses the size query parameter, the side of the image
+// in pixels
+func parseQRCodeSize(raw string) (int, error) {
+	if raw == "" {
+		return defaultQRCodeSize, nil
+	}
+
+	size, err := strconv.Atoi(raw)
+
+	if err != nil || size < 1 || size > maxQRCodeSize {
+		return 0, fmt.Errorf("size must be between 1 and %d pixels", maxQRCodeSize)
+	}
+
+	return size, nil
+}
+
+// parseQRCodeLevel parses the level query parameter, the error correction
+// level: L, M, Q or H
+func parseQRCodeLevel(raw string) (qrcode.Level, error) {
+	if raw == "" {
+		return defaultQRCodeLevel, nil
+	}
+
+	return qrcode.ParseLevel(raw)
+}
+
+// qrCodeHandler renders the QR code of a short link as a PNG image or a SVG
+// document. The PNG uses the largest whole number of pixels per module
+// fitting the requested size, at least one, so its modules stay sharp.
+func (c *URLShortener) qrCodeHandler(w http.ResponseWriter, r *http.Request) {
+	code, extension, _ := splitQRCodePath(r.URL.Path[len(c.expanderRoute):])
+
+	query := r.URL.Query()
+
+	size, err := parseQRCodeSize(query.Get("size"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level, err := parseQRCodeLevel(query.Get("level"))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = c.GetLink(code)
+
+	if errors.Is(err, ErrExpired) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	symbol, err := qrcode.Encode([]byte(c.shortLink(r, code)), level)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch extension {
+	case qrCodePNG:
+		w.Header().Set("Content-Type", "image/png")
+		png.Encode(w, symbol.Image(size/symbol.Modules()))
+	case qrCodeSVG:
+		w.Header().Set("Content-Type", "image/svg+xml")
+		symbol.WriteSVG(w, size)
+	}
+}
@@ -0,0 +1,103 @@
+package shorten
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQRCodeHandler(t *testing.T) {
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return now
+	}
+
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+	sut.putLink("expired", Link{URL: "https://wttr.in/Rome", ExpiresAt: now.Add(-time.Hour)})
+
+	tests := []struct {
+		path            string
+		wantStatus      int
+		wantContentType string
+	}{
+		{"/4611ce1.png", http.StatusOK, "image/png"},
+		{"/4611ce1.svg", http.StatusOK, "image/svg+xml"},
+		{"/4611ce1.png?size=512&level=H", http.StatusOK, "image/png"},
+		{"/4611ce1.svg?level=q", http.StatusOK, "image/svg+xml"},
+		{"/4611ce1.png?size=0", http.StatusBadRequest, "text/plain; charset=utf-8"},
+		{"/4611ce1.png?size=4096", http.StatusBadRequest, "text/plain; charset=utf-8"},
+		{"/4611ce1.png?level=X", http.StatusBadRequest, "text/plain; charset=utf-8"},
+		{"/1234567.png", http.StatusNotFound, "text/plain; charset=utf-8"},
+		{"/expired.svg", http.StatusGone, "text/plain; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", test.path, nil)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != test.wantStatus {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.path, responseRecorder.Code, test.wantStatus)
+		}
+
+		if contentType := responseRecorder.Header().Get("Content-Type"); contentType != test.wantContentType {
+			t.Errorf("Incorrect content type for %s, got: %s, want: %s.", test.path, contentType, test.wantContentType)
+		}
+	}
+
+	if count := sut.statistics.ServerStats.Handlers[QRCodeHandlerIndex].Count; count != int64(len(tests)) {
+		t.Errorf("Incorrect QR code handler count, got: %v, want: %v.", count, len(tests))
+	}
+
+	if count := sut.statistics.ServerStats.Redirects.Success + sut.statistics.ServerStats.Redirects.Failed; count != 0 {
+		t.Errorf("Incorrect redirects count, got: %v, want: %v.", count, 0)
+	}
+}
+
+func TestQRCodeHandlerSize(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	tests := []struct {
+		size     string
+		wantSide int
+	}{
+		// http://example.com/4611ce1 fits a 25 modules version 2 symbol at
+		// level M, 33 modules with the quiet zone
+		{"", 231},
+		{"330", 330},
+		{"10", 33},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/4611ce1.png?size="+test.size, nil)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.qrCodeHandler(responseRecorder, request)
+
+		img, err := png.Decode(responseRecorder.Body)
+
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if side := img.Bounds().Dx(); side != test.wantSide || img.Bounds().Dy() != test.wantSide {
+			t.Errorf("Incorrect image side for size %q, got: %v, want: %v.", test.size, side, test.wantSide)
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/4611ce1.svg?size=300", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.qrCodeHandler(responseRecorder, request)
+
+	if body := responseRecorder.Body.String(); !strings.HasPrefix(body, `<svg xmlns="http://www.w3.org/2000/svg" width="300" height="300" viewBox="0 0 33 33"`) {
+		t.Errorf("Incorrect SVG, got: %s.", body)
+	}
+}
@@ -0,0 +1,247 @@
+package shorten
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitedMessage the message of the responses refused by a rate limit
+const rateLimitedMessage = "too many requests"
+
+// maxRateLimitKeys bounds the clients tracked by a rate limiter, beyond it
+// the least recently seen client is forgotten
+const maxRateLimitKeys = 100000
+
+// ErrInvalidRateLimit returned by RateLimit.Validate
+var ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+// RateLimit a token bucket limit: Rate tokens per second are added to a
+// bucket holding at most Burst tokens, every request takes a token
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Validate checks the limit lets requests through: a positive finite rate
+// and a burst of at least one token
+func (l RateLimit) Validate() error {
+	if !(l.Rate > 0) || math.IsInf(l.Rate, 0) {
+		return fmt.Errorf("%w: rate must be positive, got: %v", ErrInvalidRateLimit, l.Rate)
+	}
+
+	if l.Burst < 1 {
+		return fmt.Errorf("%w: burst must be at least 1, got: %v", ErrInvalidRateLimit, l.Burst)
+	}
+
+	return nil
+}
+
+// limitKind the kind of requests a rate limiter applies to
+type limitKind int
+
+// Kinds of rate limited requests
+const (
+	createLimit limitKind = iota
+	redirectLimit
+)
+
+// rateLimiter keeps a token bucket per client, at most maxKeys of them: the
+// buckets are kept in a list from the most to the least recently used, so
+// the one to forget is found in constant time
+type rateLimiter struct {
+	kind    limitKind
+	limit   RateLimit
+	maxKeys int
+
+	mux     sync.Mutex
+	buckets map[string]*list.Element
+	recent  *list.List
+}
+
+type tokenBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(kind limitKind, limit RateLimit) *rateLimiter {
+	limiter := rateLimiter{}
+
+	limiter.kind = kind
+	limiter.limit = limit
+	limiter.maxKeys = maxRateLimitKeys
+	limiter.buckets = make(map[string]*list.Element)
+	limiter.recent = list.New()
+
+	return &limiter
+}
+
+// allow takes a token from the bucket of key, when none is left it returns
+// how long until the next token is available
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	return l.allowN(key, 1, now)
+}
+
+// allowN takes n tokens at once from the bucket of key, when fewer are left
+// it returns how long until they are available. n must not exceed the burst.
+func (l *rateLimiter) allowN(key string, n int, now time.Time) (bool, time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	element, ok := l.buckets[key]
+	if ok {
+		l.recent.MoveToFront(element)
+	} else {
+		if len(l.buckets) >= l.maxKeys {
+			l.forgetLeastRecent()
+		}
+
+		element = l.recent.PushFront(&tokenBucket{key, float64(l.limit.Burst), now})
+		l.buckets[key] = element
+	}
+
+	bucket := element.Value.(*tokenBucket)
+	bucket.refill(l.limit, now)
+
+	if bucket.tokens >= float64(n) {
+		bucket.tokens -= float64(n)
+		return true, 0
+	}
+
+	wait := (float64(n) - bucket.tokens) / l.limit.Rate
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+func (b *tokenBucket) refill(limit RateLimit, now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.last = now
+}
+
+// forgetLeastRecent drops the bucket of the client seen least recently
+func (l *rateLimiter) forgetLeastRecent() {
+	oldest := l.recent.Back()
+	if oldest == nil {
+		return
+	}
+
+	l.recent.Remove(oldest)
+	delete(l.buckets, oldest.Value.(*tokenBucket).key)
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR networks of the proxies
+// whose X-Forwarded-For header is trusted
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func (c *URLShortener) isTrustedProxy(ip net.IP) bool {
+	for _, network := range c.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the address of the client of r. X-Forwarded-For is
+// honored only when received from a trusted proxy, the client is the
+// rightmost address not belonging to a trusted proxy.
+func (c *URLShortener) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !c.isTrustedProxy(ip) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+		if !c.isTrustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip.String()
+}
+
+// allowRequest tells if the client of r is within the limit of limiter,
+// when it is not the Retry-After header is set and the caller is expected to
+// answer with 429
+func (c *URLShortener) allowRequest(w http.ResponseWriter, r *http.Request, limiter *rateLimiter) bool {
+	return c.allowRequestN(w, r, limiter, 1)
+}
+
+// allowRequestN is allowRequest charging n tokens, as for the n links of a
+// bulk import
+func (c *URLShortener) allowRequestN(w http.ResponseWriter, r *http.Request, limiter *rateLimiter, n int) bool {
+	if limiter == nil {
+		return true
+	}
+
+	allowed, retryAfter := limiter.allowN(c.clientIP(r), n, c.now())
+	if allowed {
+		return true
+	}
+
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	c.statistics.rateLimited(limiter.kind)
+
+	return false
+}
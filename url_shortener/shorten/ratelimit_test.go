@@ -0,0 +1,214 @@
+package shorten
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	sut := newRateLimiter(createLimit, RateLimit{Rate: 1, Burst: 2})
+
+	start := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key            string
+		at             time.Duration
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+	}{
+		{"192.0.2.1", 0, true, 0},
+		{"192.0.2.1", 0, true, 0},
+		{"192.0.2.1", 0, false, time.Second},
+		{"192.0.2.2", 0, true, 0},
+		{"192.0.2.1", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		{"192.0.2.1", time.Second, true, 0},
+		{"192.0.2.1", 10 * time.Second, true, 0},
+		{"192.0.2.1", 10 * time.Second, true, 0},
+		{"192.0.2.1", 10 * time.Second, false, time.Second},
+	}
+
+	for _, test := range tests {
+		allowed, retryAfter := sut.allow(test.key, start.Add(test.at))
+
+		if allowed != test.wantAllowed {
+			t.Errorf("Incorrect allowed for %s at %s, got: %v, want: %v.", test.key, test.at, allowed, test.wantAllowed)
+		}
+
+		if retryAfter != test.wantRetryAfter {
+			t.Errorf("Incorrect retry after for %s at %s, got: %v, want: %v.", test.key, test.at, retryAfter, test.wantRetryAfter)
+		}
+	}
+}
+
+func TestRateLimiterBoundsKeys(t *testing.T) {
+	sut := newRateLimiter(createLimit, RateLimit{Rate: 1, Burst: 1})
+	sut.maxKeys = 2
+
+	now := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key         string
+		wantAllowed bool
+	}{
+		{"192.0.2.1", true},
+		{"192.0.2.2", true},
+		{"192.0.2.1", false},
+		{"192.0.2.3", true},
+		{"192.0.2.1", false},
+		{"192.0.2.2", true},
+	}
+
+	for _, test := range tests {
+		if allowed, _ := sut.allow(test.key, now); allowed != test.wantAllowed {
+			t.Errorf("Incorrect allowed for %s, got: %v, want: %v.", test.key, allowed, test.wantAllowed)
+		}
+
+		if len(sut.buckets) > sut.maxKeys || sut.recent.Len() != len(sut.buckets) {
+			t.Errorf("Incorrect tracked clients, got: %v and %v, want at most: %v.", len(sut.buckets), sut.recent.Len(), sut.maxKeys)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.10"})
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	sut := NewURLShortener(WithTrustedProxies(proxies...))
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		wantIP       string
+	}{
+		{"198.51.100.7:1234", "", "198.51.100.7"},
+		{"198.51.100.7:1234", "203.0.113.1", "198.51.100.7"},
+		{"10.1.2.3:1234", "203.0.113.1", "203.0.113.1"},
+		{"10.1.2.3:1234", "203.0.113.1, 203.0.113.2, 192.0.2.10", "203.0.113.2"},
+		{"192.0.2.10:1234", "10.0.0.1, 10.0.0.2", "10.0.0.1"},
+		{"10.1.2.3:1234", "not-an-ip", "10.1.2.3"},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+
+		if got := sut.clientIP(request); got != test.wantIP {
+			t.Errorf("Incorrect client IP for %s via %s, got: %s, want: %s.", test.forwardedFor, test.remoteAddr, got, test.wantIP)
+		}
+	}
+
+	if _, err := ParseTrustedProxies([]string{"proxy.example"}); err == nil {
+		t.Errorf("Expected error for an invalid trusted proxy.")
+	}
+}
+
+func TestRateLimitedHandlers(t *testing.T) {
+	sut := NewURLShortener(WithCreateRateLimit(RateLimit{Rate: 0.1, Burst: 1}), WithRedirectRateLimit(RateLimit{Rate: 0.1, Burst: 1}))
+	sut.addURL("https://wttr.in/Florence", "f495791")
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		method         string
+		path           string
+		wantStatusCode int
+	}{
+		{"GET", "/shorten?url=https://wttr.in/Rome", http.StatusOK},
+		{"GET", "/shorten?url=https://wttr.in/Rome", http.StatusTooManyRequests},
+		{"POST", "/api/v1/links", http.StatusTooManyRequests},
+		{"GET", "/f495791", http.StatusSeeOther},
+		{"GET", "/f495791", http.StatusTooManyRequests},
+		{"GET", "/statistics", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest(test.method, test.path, nil))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s %s, got: %v, want: %v.", test.method, test.path, responseRecorder.Code, test.wantStatusCode)
+		}
+
+		if retryAfter := responseRecorder.Header().Get("Retry-After"); test.wantStatusCode == http.StatusTooManyRequests && retryAfter != "10" {
+			t.Errorf("Incorrect Retry-After, got: %s, want: %s.", retryAfter, "10")
+		}
+	}
+
+	got := sut.statistics.ServerStats.RateLimited
+
+	if got.Create != 2 || got.Redirect != 1 {
+		t.Errorf("Incorrect rate limited statistics, got: %v and %v, want: %v and %v.", got.Create, got.Redirect, 2, 1)
+	}
+
+	wantLimit := RateLimit{Rate: 0.1, Burst: 1}
+	if got.CreateLimit == nil || *got.CreateLimit != wantLimit || got.RedirectLimit == nil || *got.RedirectLimit != wantLimit {
+		t.Errorf("Incorrect rate limits in statistics, got: %v and %v, want: %v.", got.CreateLimit, got.RedirectLimit, wantLimit)
+	}
+
+	if limits := NewURLShortener().statistics.ServerStats.RateLimited; limits.CreateLimit != nil || limits.RedirectLimit != nil {
+		t.Errorf("Incorrect rate limits when disabled, got: %v and %v, want: none.", limits.CreateLimit, limits.RedirectLimit)
+	}
+}
+
+func TestRateLimitValidate(t *testing.T) {
+	tests := []struct {
+		limit     RateLimit
+		wantError bool
+	}{
+		{RateLimit{Rate: 0.5, Burst: 1}, false},
+		{RateLimit{Rate: 10, Burst: 100}, false},
+		{RateLimit{Rate: 10, Burst: 0}, true},
+		{RateLimit{Rate: 10, Burst: -1}, true},
+		{RateLimit{Rate: 0, Burst: 10}, true},
+		{RateLimit{Rate: -1, Burst: 10}, true},
+		{RateLimit{Rate: math.Inf(1), Burst: 10}, true},
+		{RateLimit{Rate: math.NaN(), Burst: 10}, true},
+	}
+
+	for _, test := range tests {
+		err := test.limit.Validate()
+
+		if (err != nil) != test.wantError {
+			t.Errorf("Incorrect error for %v, got: %v, want error: %v.", test.limit, err, test.wantError)
+		}
+
+		if err != nil && !errors.Is(err, ErrInvalidRateLimit) {
+			t.Errorf("Incorrect error for %v, got: %v, want: %v.", test.limit, err, ErrInvalidRateLimit)
+		}
+	}
+}
+
+func TestRateLimitOptionsIgnoreInvalidLimits(t *testing.T) {
+	tests := []struct {
+		limit       RateLimit
+		wantLimiter bool
+	}{
+		{RateLimit{Rate: 0.5, Burst: 1}, true},
+		{RateLimit{Rate: 10, Burst: 0}, false},
+		{RateLimit{Rate: 0, Burst: 10}, false},
+		{RateLimit{Rate: math.NaN(), Burst: 10}, false},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener(WithCreateRateLimit(test.limit), WithRedirectRateLimit(test.limit))
+
+		if (sut.createLimiter != nil) != test.wantLimiter {
+			t.Errorf("Incorrect create limiter for %v, got: %v, want: %v.", test.limit, sut.createLimiter != nil, test.wantLimiter)
+		}
+
+		if (sut.redirectLimiter != nil) != test.wantLimiter {
+			t.Errorf("Incorrect redirect limiter for %v, got: %v, want: %v.", test.limit, sut.redirectLimiter != nil, test.wantLimiter)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRedirectCode is returned for redirect status codes not supported
+var ErrInvalidRedirectCode = errors.New("invalid redirect code")
+
+// defaultRedirectCode the redirect status code used unless configured
+const defaultRedirectCode = http.StatusSeeOther
+
+// IsRedirectCode tells if code is a redirect status code links may use:
+// 301, 302, 303, 307 or 308
+func IsRedirectCode(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+
+	return false
+}
+
+// parseRedirectCode parses the redirect status code requested for a link,
+// empty means the server default
+func parseRedirectCode(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	code, err := strconv.Atoi(raw)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidRedirectCode, raw)
+	}
+
+	return code, validateRedirectCode(code)
+}
+
+// validateRedirectCode checks the redirect status code of a link, zero means
+// the server default
+func validateRedirectCode(code int) error {
+	if code != 0 && !IsRedirectCode(code) {
+		return fmt.Errorf("%w: %d", ErrInvalidRedirectCode, code)
+	}
+
+	return nil
+}
+
+// redirectCode returns the status code to redirect the link with
+func (c *URLShortener) redirectCode(link Link) int {
+	if link.RedirectCode != 0 {
+		return link.RedirectCode
+	}
+
+	return c.defaultRedirectCode
+}
+
+// destination returns where to redirect the request r for the link. With
+// query passthrough the query of r is appended to the one of the long URL,
+// before its fragment. Fragments never reach the server: clients keep theirs
+// across the redirect when the long URL has none.
+func (c *URLShortener) destination(link Link, r *http.Request) string {
+	if !c.passQuery || r.URL.RawQuery == "" {
+		return link.URL
+	}
+
+	base, fragment := link.URL, ""
+	if i := strings.IndexByte(base, '#'); i >= 0 {
+		base, fragment = base[:i], base[i:]
+	}
+
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+		if strings.HasSuffix(base, "?") || strings.HasSuffix(base, "&") {
+			separator = ""
+		}
+	}
+
+	return base + separator + r.URL.RawQuery + fragment
+}
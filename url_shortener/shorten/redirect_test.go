@@ -0,0 +1,93 @@
+package shorten
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRedirectCode(t *testing.T) {
+	tests := []struct {
+		raw      string
+		wantCode int
+		wantErr  error
+	}{
+		{"", 0, nil},
+		{"301", http.StatusMovedPermanently, nil},
+		{"308", http.StatusPermanentRedirect, nil},
+		{"200", 200, ErrInvalidRedirectCode},
+		{"moved", 0, ErrInvalidRedirectCode},
+	}
+
+	for _, test := range tests {
+		code, err := parseRedirectCode(test.raw)
+
+		if code != test.wantCode || !errors.Is(err, test.wantErr) {
+			t.Errorf("Incorrect redirect code for %q, got: %v %v, want: %v %v.", test.raw, code, err, test.wantCode, test.wantErr)
+		}
+	}
+}
+
+func TestRedirectCodes(t *testing.T) {
+	sut := NewURLShortener(WithRedirectCode(http.StatusFound))
+
+	tests := []struct {
+		path           string
+		wantStatusCode int
+	}{
+		{"/shorten?url=https://wttr.in/Rome", http.StatusOK},
+		{"/shorten?url=https://wttr.in/Florence&alias=florence&redirect_code=308", http.StatusOK},
+		{"/shorten?url=https://wttr.in/Milan&redirect_code=200", http.StatusBadRequest},
+		{"/87aefef", http.StatusFound},
+		{"/florence", http.StatusPermanentRedirect},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.path, nil))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.path, responseRecorder.Code, test.wantStatusCode)
+		}
+	}
+
+	if NewURLShortener(WithRedirectCode(http.StatusOK)).defaultRedirectCode != http.StatusSeeOther {
+		t.Errorf("Unexpected default redirect code set to %v.", http.StatusOK)
+	}
+}
+
+func TestQueryPassthrough(t *testing.T) {
+	tests := []struct {
+		longURL      string
+		target       string
+		passQuery    bool
+		wantLocation string
+	}{
+		{"https://wttr.in/Florence", "/abc?utm_source=x", false, "https://wttr.in/Florence"},
+		{"https://wttr.in/Florence", "/abc?utm_source=x", true, "https://wttr.in/Florence?utm_source=x"},
+		{"https://wttr.in/Florence", "/abc", true, "https://wttr.in/Florence"},
+		{"https://wttr.in/Florence?format=3", "/abc?utm_source=x&lang=it", true, "https://wttr.in/Florence?format=3&utm_source=x&lang=it"},
+		{"https://wttr.in/Florence?format=3#now", "/abc?utm_source=x", true, "https://wttr.in/Florence?format=3&utm_source=x#now"},
+		{"https://wttr.in/Florence#now", "/abc?utm_source=x", true, "https://wttr.in/Florence?utm_source=x#now"},
+	}
+
+	for _, test := range tests {
+		options := []Option{}
+		if test.passQuery {
+			options = append(options, WithQueryPassthrough())
+		}
+
+		sut := NewURLShortener(options...)
+		sut.addURL(test.longURL, "abc")
+
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.target, nil))
+
+		if location := responseRecorder.Header().Get("Location"); location != test.wantLocation {
+			t.Errorf("Incorrect location for %s, got: %s, want: %s.", test.target, location, test.wantLocation)
+		}
+	}
+}
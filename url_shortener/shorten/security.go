@@ -0,0 +1,41 @@
+package shorten
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+)
+
+// contentSecurityPolicy allows nothing but the copy script of the shortened
+// page, images from the server and no framing
+var contentSecurityPolicy = "default-src 'none'; script-src '" + scriptHash(copyScript) +
+	"'; img-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// strictTransportSecurity keeps browsers on HTTPS for two years once they
+// reached the server over it
+const strictTransportSecurity = "max-age=63072000"
+
+// scriptHash the Content-Security-Policy source of an inline script
+func scriptHash(script string) string {
+	sum := sha256.Sum256([]byte(script))
+
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// secure wraps a handler setting the security headers on its responses
+func (c *URLShortener) secure(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+
+		header.Set("Content-Security-Policy", contentSecurityPolicy)
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+
+		if scheme, _ := c.requestHost(r); scheme == "https" {
+			header.Set("Strict-Transport-Security", strictTransportSecurity)
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,152 @@
+package shorten
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// maliciousURLs try to break out of the attributes and the text of the pages
+var maliciousURLs = []string{
+	`https://evil.example/"><script>alert(1)</script>`,
+	`https://evil.example/'><img src=x onerror=alert(1)>`,
+	`https://evil.example/</a><svg onload=alert(1)>`,
+	`https://evil.example/?q=<iframe src=javascript:alert(1)>`,
+	`https://evil.example/#"onmouseover="alert(1)`,
+	`javascript:alert(1)`,
+	`data:text/html,<script>alert(1)</script>`,
+}
+
+// unescaped markup the malicious URLs would reflect into a page
+var unescapedMarkup = []string{"<script>alert", "<img", "<svg", "<iframe", `"onmouseover=`, `href="javascript:`, `href="data:`}
+
+func assertEscaped(t *testing.T, name string, body string) {
+	t.Helper()
+
+	for _, markup := range unescapedMarkup {
+		if strings.Contains(body, markup) {
+			t.Errorf("Unescaped %q in %s body: %s.", markup, name, body)
+		}
+	}
+}
+
+func TestShortenHandlerEscapesLinks(t *testing.T) {
+	accepts := []string{"", "text/html", "text/plain", "application/json"}
+
+	for _, maliciousURL := range maliciousURLs {
+		for _, accept := range accepts {
+			sut := NewURLShortener()
+
+			request := httptest.NewRequest("GET", "/shorten?url="+url.QueryEscape(maliciousURL), nil)
+			request.Header.Set("Accept", accept)
+			responseRecorder := httptest.NewRecorder()
+
+			sut.ServeHTTP(responseRecorder, request)
+
+			// other media types are not rendered as long as they are not sniffed
+			contentType := responseRecorder.Header().Get("Content-Type")
+			if strings.HasPrefix(contentType, "text/html") {
+				assertEscaped(t, "shorten of "+maliciousURL, responseRecorder.Body.String())
+			} else if nosniff := responseRecorder.Header().Get("X-Content-Type-Options"); nosniff != "nosniff" {
+				t.Errorf("Missing nosniff on %s response to %s.", contentType, maliciousURL)
+			}
+
+			if responseRecorder.Code != http.StatusOK && strings.HasPrefix(contentType, "text/html") {
+				t.Errorf("Incorrect error content type, got: %s.", contentType)
+			}
+		}
+	}
+}
+
+func TestPagesEscapeStoredLinks(t *testing.T) {
+	// links from persistence files are not validated
+	for _, maliciousURL := range maliciousURLs {
+		sut := NewURLShortener()
+		sut.putLink("plain", Link{URL: maliciousURL})
+		sut.putLink("warned", Link{URL: maliciousURL, Interstitial: true})
+
+		paths := []string{"/plain+", "/warned"}
+		for _, path := range paths {
+			responseRecorder := httptest.NewRecorder()
+
+			sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", path, nil))
+
+			assertEscaped(t, path+" of "+maliciousURL, responseRecorder.Body.String())
+		}
+
+		for _, accept := range []string{"", "text/html"} {
+			request := httptest.NewRequest("GET", "/", nil)
+			responseRecorder := httptest.NewRecorder()
+			request.Header.Set("Accept", accept)
+
+			link := sut.newLinkJSON(request, "plain", Link{URL: maliciousURL})
+			if accept == "" {
+				shortenedFragment.Execute(responseRecorder, link)
+			} else {
+				shortenedPage.Execute(responseRecorder, link)
+			}
+
+			assertEscaped(t, "shortened "+accept+" of "+maliciousURL, responseRecorder.Body.String())
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	paths := []string{"/shorten?url=https://wttr.in/Rome", "/4611ce1", "/4611ce1+", "/4611ce1.svg", "/statistics", "/api/v1/links", "/1234567"}
+	wantHeaders := map[string]string{
+		"Content-Security-Policy": contentSecurityPolicy,
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+	}
+
+	for _, path := range paths {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", path, nil))
+
+		for name, want := range wantHeaders {
+			if got := responseRecorder.Header().Get(name); got != want {
+				t.Errorf("Incorrect %s header of %s, got: %s, want: %s.", name, path, got, want)
+			}
+		}
+
+		if hsts := responseRecorder.Header().Get("Strict-Transport-Security"); hsts != "" {
+			t.Errorf("Unexpected Strict-Transport-Security over HTTP: %s.", hsts)
+		}
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", "https://localhost/4611ce1", nil))
+
+	if hsts := responseRecorder.Header().Get("Strict-Transport-Security"); hsts != strictTransportSecurity {
+		t.Errorf("Incorrect Strict-Transport-Security, got: %s, want: %s.", hsts, strictTransportSecurity)
+	}
+}
+
+func TestContentSecurityPolicyAllowsCopyScript(t *testing.T) {
+	sut := NewURLShortener()
+
+	request := httptest.NewRequest("GET", "/shorten?url=https://wttr.in/Rome", nil)
+	request.Header.Set("Accept", "text/html")
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	body := responseRecorder.Body.String()
+	start := strings.Index(body, "<script>") + len("<script>")
+	end := strings.Index(body, "</script>")
+
+	if start < len("<script>") || end < start {
+		t.Fatalf("Missing script in body: %s.", body)
+	}
+
+	if hash := scriptHash(body[start:end]); !strings.Contains(contentSecurityPolicy, "'"+hash+"'") {
+		t.Errorf("Incorrect script hash, got: %s, want it in: %s.", hash, contentSecurityPolicy)
+	}
+}
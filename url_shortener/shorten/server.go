@@ -2,38 +2,98 @@ package shorten
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
+// maxAttempts bounds the short URL candidates tried for a long URL
+const maxAttempts = 1024
+
 // URLShortener URL shortener server data structure
 type URLShortener struct {
 	expanderRoute   string
 	shortenRoute    string
 	statisticsRoute string
+	apiRoute        string
+	openAPIRoute    string
+	bulkRoute       string
+	metricsRoute    string
+	routePrefix     string
+
+	router    *http.ServeMux
+	setupOnce sync.Once
+
+	store     Store
+	wal       *WAL
+	generator CodeGenerator
+
+	allowedSchemes []string
+	selfHosts      []string
+	sortQuery      bool
 
-	mappings map[string]string
+	createLimiter   *rateLimiter
+	redirectLimiter *rateLimiter
+	trustedProxies  []*net.IPNet
+
+	apiKeys    *APIKeys
+	adminToken string
+
+	defaultRedirectCode int
+	passQuery           bool
+
+	publicURL      *url.URL
+	trustForwarded bool
 
 	statistics StatsJSON
+	analytics  linkAnalytics
+
+	now func() time.Time
 
 	mux sync.Mutex
 }
 
-// NewURLShortener a URLShortener constructor
-func NewURLShortener() *URLShortener {
+// NewURLShortener a URLShortener constructor, by default the mappings are
+// kept in a MemoryStore
+func NewURLShortener(options ...Option) *URLShortener {
 	urlShortener := URLShortener{}
 
 	urlShortener.expanderRoute = "/"
 	urlShortener.shortenRoute = "/shorten"
 	urlShortener.statisticsRoute = "/statistics"
+	urlShortener.apiRoute = "/api/v1/links"
+	urlShortener.openAPIRoute = "/api/v1/openapi.json"
+	urlShortener.bulkRoute = "/api/bulk"
+	urlShortener.metricsRoute = "/metrics"
 
-	urlShortener.mappings = make(map[string]string)
+	urlShortener.store = NewMemoryStore()
+	urlShortener.generator = NewHashGenerator()
+
+	urlShortener.allowedSchemes = []string{"http", "https"}
+
+	urlShortener.defaultRedirectCode = defaultRedirectCode
 
 	urlShortener.statistics = NewStatsJSON()
 
+	urlShortener.now = time.Now
+
+	for _, option := range options {
+		option(&urlShortener)
+	}
+
+	urlShortener.mountRoutes()
+	urlShortener.nameHandlers()
+	urlShortener.statistics.setRateLimits(urlShortener.createLimiter, urlShortener.redirectLimiter)
+	urlShortener.router = urlShortener.newRouter()
+
+	urlShortener.refreshTotalURL()
+
 	return &urlShortener
 }
 
@@ -42,72 +102,547 @@ func NewURLShortener() *URLShortener {
 func (c *URLShortener) UnpersistFrom(r io.Reader) error {
 	decoder := json.NewDecoder(r)
 
-	if err := decoder.Decode(&c.mappings); err != nil {
+	mappings := make(map[string]Link)
+
+	if err := decoder.Decode(&mappings); err != nil {
 		return err
 	}
 
-	c.statistics.updateTotalURL(int64(len(c.mappings)))
-	return nil
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	for shortURL, link := range mappings {
+		if err := c.store.Put(shortURL, link); err != nil {
+			return err
+		}
+	}
+
+	c.seedGenerator()
+
+	return c.refreshTotalURL()
+}
+
+// ReplayWAL function applies the write-ahead log records read from the reader
+// passed in on top of the URL mappings, it returns the number of records
+// applied
+func (c *URLShortener) ReplayWAL(r io.Reader) (int, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	applied := 0
+
+	err := readWAL(r, func(record walRecord) error {
+		switch record.Op {
+		case walOpPut:
+			if err := c.store.Put(record.ShortURL, record.link()); err != nil {
+				return err
+			}
+		case walOpDelete:
+			if err := c.store.Delete(record.ShortURL); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown WAL operation: %s", record.Op)
+		}
+
+		applied++
+		return nil
+	})
+
+	if err != nil {
+		return applied, err
+	}
+
+	c.seedGenerator()
+
+	return applied, c.refreshTotalURL()
+}
+
+// seedGenerator lets the code generator catch up with the codes loaded, so
+// a counter persisted before a crash does not collide with every link
+// created after it. The caller must hold the mutex.
+func (c *URLShortener) seedGenerator() {
+	generator, ok := c.generator.(seeder)
+	if !ok {
+		return
+	}
+
+	generator.seed(func(code string) bool {
+		_, err := c.store.Get(code)
+		return err == nil
+	})
 }
 
 // PersistTo function encodes the URL mappings in a JSON written to the writer
 // passed in
 func (c *URLShortener) PersistTo(w io.Writer) error {
+	mappings := make(map[string]Link)
+
+	err := c.store.Iterate(func(shortURL string, link Link) bool {
+		mappings[shortURL] = link
+		return true
+	})
+
+	if err != nil {
+		return err
+	}
+
 	encoder := json.NewEncoder(w)
 
-	if err := encoder.Encode(c.mappings); err != nil {
+	if err := encoder.Encode(mappings); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// SetupHandlerFunctions setups handler functions
+// mountRoutes prepends the route prefix, when set, to every route
+func (c *URLShortener) mountRoutes() {
+	if !strings.HasSuffix(c.expanderRoute, "/") {
+		c.expanderRoute += "/"
+	}
+
+	prefix := strings.Trim(c.routePrefix, "/")
+	if prefix == "" {
+		return
+	}
+
+	routes := []*string{&c.expanderRoute, &c.shortenRoute, &c.statisticsRoute, &c.apiRoute, &c.openAPIRoute, &c.bulkRoute, &c.metricsRoute}
+	for _, route := range routes {
+		*route = "/" + prefix + *route
+	}
+}
+
+// nameHandlers names the handlers in the statistics after the routes they
+// are mounted on
+func (c *URLShortener) nameHandlers() {
+	c.statistics.nameHandler(ShortenHandlerIndex, c.shortenRoute)
+	c.statistics.nameHandler(StatisticsHandlerIndex, c.statisticsRoute)
+	c.statistics.nameHandler(ExpanderHandlerIndex, c.expanderRoute)
+	c.statistics.nameHandler(APIHandlerIndex, c.apiRoute)
+	c.statistics.nameHandler(MetricsHandlerIndex, c.metricsRoute)
+	c.statistics.nameHandler(QRCodeHandlerIndex, c.expanderRoute+"{code}.{png,svg}")
+	c.statistics.nameHandler(PreviewHandlerIndex, c.expanderRoute+"{code}"+previewSuffix)
+}
+
+// patterns returns the handler of every ServeMux pattern served, only the
+// redirects with their QR codes and previews and the OpenAPI document are
+// public when API keys are required
+func (c *URLShortener) patterns() map[string]http.HandlerFunc {
+	shorten := c.authenticate(c.shortenHandler, writeTextError)
+	statistics := c.authenticate(c.statisticsHandler, writeTextError)
+	api := c.authenticate(c.apiHandler, writeJSONError)
+	bulk := c.authenticate(c.bulkHandler, writeJSONError)
+	metrics := c.authenticate(c.metricsHandler, writeTextError)
+	expander := c.instrument(ExpanderHandlerIndex, c.expanderHandler)
+	qrCode := c.instrument(QRCodeHandlerIndex, c.qrCodeHandler)
+	preview := c.instrument(PreviewHandlerIndex, c.previewHandler)
+
+	return map[string]http.HandlerFunc{
+		c.shortenRoute:          c.instrument(ShortenHandlerIndex, shorten),
+		c.statisticsRoute:       c.instrument(StatisticsHandlerIndex, statistics),
+		c.statisticsRoute + "/": c.instrument(StatisticsHandlerIndex, statistics),
+		c.apiRoute:              c.instrument(APIHandlerIndex, api),
+		c.apiRoute + "/":        c.instrument(APIHandlerIndex, api),
+		c.openAPIRoute:          c.instrument(APIHandlerIndex, c.openAPIHandler),
+		c.bulkRoute:             c.instrument(APIHandlerIndex, bulk),
+		c.metricsRoute:          c.instrument(MetricsHandlerIndex, metrics),
+		c.expanderRoute:         c.codeHandler(expander, qrCode, preview),
+	}
+}
+
+// codeHandler routes the requests below the expander route: the codes with
+// an image extension get their QR code, the codes followed by a + their
+// preview, the others are redirected
+func (c *URLShortener) codeHandler(expander, qrCode, preview http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := splitQRCodePath(r.URL.Path); ok {
+			qrCode(w, r)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, previewSuffix) {
+			preview(w, r)
+			return
+		}
+
+		expander(w, r)
+	}
+}
+
+func (c *URLShortener) newRouter() *http.ServeMux {
+	router := http.NewServeMux()
+
+	for pattern, handler := range c.patterns() {
+		router.HandleFunc(pattern, c.secure(handler))
+	}
+
+	return router
+}
+
+// ServeMux returns the ServeMux routing the requests of the URLShortener
+func (c *URLShortener) ServeMux() *http.ServeMux {
+	return c.router
+}
+
+// ServeHTTP serves a request routing it to the URLShortener handlers
+func (c *URLShortener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.router.ServeHTTP(w, r)
+}
+
+// SetupHandlerFunctions setups handler functions on http.DefaultServeMux,
+// calling it again has no effect
+//
+// Deprecated: serve the URLShortener itself, it is an http.Handler.
 func (c *URLShortener) SetupHandlerFunctions() {
-	http.HandleFunc(c.shortenRoute, c.shortenHandler)
-	http.HandleFunc(c.statisticsRoute, c.statisticsHandler)
-	http.HandleFunc(c.expanderRoute, c.expanderHandler)
+	c.setupOnce.Do(func() {
+		for pattern := range c.patterns() {
+			http.Handle(pattern, c)
+		}
+	})
 }
 
-func (c *URLShortener) addURL(longURL, shortURL string) {
+// refreshTotalURL updates the total URL statistic from the store size
+func (c *URLShortener) refreshTotalURL() error {
+	totalURL, err := c.store.Len()
+
+	if err != nil {
+		return err
+	}
+
+	c.statistics.updateTotalURL(int64(totalURL))
+	return nil
+}
+
+func (c *URLShortener) addURL(longURL, shortURL string) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	c.mappings[shortURL] = longURL
+	return c.putLink(shortURL, c.stamp(Link{URL: longURL}))
+}
+
+// stamp returns the link created now, to the second
+func (c *URLShortener) stamp(link Link) Link {
+	link.CreatedAt = c.now().UTC().Truncate(time.Second)
 
-	c.statistics.updateTotalURL(int64(len(c.mappings)))
+	return link
 }
 
-// GetURL returns the complete URL corresponding to the shortened URL
-func (c *URLShortener) GetURL(shortURL string) (string, error) {
+// isFree tells if a short URL can be taken: expired links are as good as
+// removed, even before being reaped
+func (c *URLShortener) isFree(shortURL string) (bool, Link, error) {
+	link, err := c.store.Get(shortURL)
+
+	if err == ErrNotFound {
+		return true, link, nil
+	}
+
+	if err != nil {
+		return false, link, err
+	}
+
+	return link.isExpired(c.now()), link, nil
+}
+
+// shortenURL stores the link under a short URL not taken by any other link
+// and returns it, asking the code generator for a new candidate on
+// collisions. When a candidate already holds the same link, without expiry
+// as the new one, it is returned as is with the boolean set, so
+// deterministic generators never duplicate a mapping.
+func (c *URLShortener) shortenURL(link Link) (string, bool, error) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	longURL, ok := c.mappings[shortURL]
+	candidate, existing, err := c.generateCode(link)
 
-	if !ok {
-		return "", fmt.Errorf("short URL not found: %s", shortURL)
+	if err != nil || existing {
+		return candidate, existing, err
+	}
+
+	return candidate, false, c.putLink(candidate, c.stamp(link))
+}
+
+// generateCode returns a short URL free for the link or, when the boolean is
+// true, already holding the same link. Candidates clashing with the routes
+// are skipped. The caller holds the mutex.
+func (c *URLShortener) generateCode(link Link) (string, bool, error) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		candidate, err := c.generator.Generate(link.URL, attempt)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		if c.validateCode(candidate) != nil {
+			continue
+		}
+
+		free, taken, err := c.isFree(candidate)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		if free {
+			return candidate, false, nil
+		}
+
+		if taken.sameTarget(link) {
+			return candidate, true, nil
+		}
 	}
 
-	return longURL, nil
+	return "", false, errCodeSpaceExhausted
+}
+
+// errKept is returned by deleteURLIf when the link does not satisfy the
+// condition to be deleted
+var errKept = errors.New("link kept")
+
+// deleteURL removes a mapping, it returns ErrNotFound when missing
+func (c *URLShortener) deleteURL(shortURL string) error {
+	return c.deleteURLIf(shortURL, func(Link) bool {
+		return true
+	})
 }
 
+// deleteURLIf removes a mapping if its link satisfies the condition checked
+// while holding the mutex, otherwise it returns errKept
+func (c *URLShortener) deleteURLIf(shortURL string, condition func(Link) bool) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	link, err := c.store.Get(shortURL)
+
+	if err != nil {
+		return err
+	}
+
+	if !condition(link) {
+		return errKept
+	}
+
+	if c.wal != nil {
+		record := walRecord{Op: walOpDelete, ShortURL: shortURL}
+
+		if err := c.wal.append(record); err != nil {
+			return err
+		}
+	}
+
+	if err := c.store.Delete(shortURL); err != nil {
+		return err
+	}
+
+	c.analytics.forget(shortURL)
+
+	return c.refreshTotalURL()
+}
+
+// putLink stores a mapping, the caller must hold the mutex
+func (c *URLShortener) putLink(shortURL string, link Link) error {
+	return c.writeLink(shortURL, link, true)
+}
+
+// writeLink stores a link logging it first in the write-ahead log, synced to
+// disk when asked: links written without sync must not be acknowledged
+// before syncing the log
+func (c *URLShortener) writeLink(shortURL string, link Link, sync bool) error {
+	if c.wal != nil {
+		record := walRecord{Op: walOpPut, ShortURL: shortURL, Link: &link}
+
+		if err := c.wal.write(record, sync); err != nil {
+			return err
+		}
+	}
+
+	previous, previousErr := c.store.Get(shortURL)
+
+	if err := c.store.Put(shortURL, link); err != nil {
+		return err
+	}
+
+	// analytics of an expired link taken over by another URL are dropped
+	if previousErr == nil && previous.URL != link.URL {
+		c.analytics.forget(shortURL)
+	}
+
+	return c.refreshTotalURL()
+}
+
+// RemoveExpired function removes the expired links from the mappings, it
+// returns the number of links removed
+func (c *URLShortener) RemoveExpired() (int, error) {
+	now := c.now()
+	expired := make([]string, 0)
+
+	err := c.store.Iterate(func(shortURL string, link Link) bool {
+		if link.isExpired(now) {
+			expired = append(expired, shortURL)
+		}
+		return true
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, shortURL := range expired {
+		err := c.deleteURLIf(shortURL, func(link Link) bool {
+			return link.isExpired(now)
+		})
+
+		if err == errKept {
+			continue // renewed in the meanwhile
+		}
+
+		if err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// GetLink returns the link corresponding to the shortened URL
+func (c *URLShortener) GetLink(shortURL string) (Link, error) {
+	link, err := c.store.Get(shortURL)
+
+	if err == ErrNotFound {
+		return Link{}, fmt.Errorf("%w: %s", ErrNotFound, shortURL)
+	}
+
+	if err != nil {
+		return Link{}, err
+	}
+
+	if link.isExpired(c.now()) {
+		return Link{}, fmt.Errorf("%w: %s", ErrExpired, shortURL)
+	}
+
+	return link, nil
+}
+
+// GetURL returns the complete URL corresponding to the shortened URL
+func (c *URLShortener) GetURL(shortURL string) (string, error) {
+	link, err := c.GetLink(shortURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	return link.URL, nil
+}
+
+// shortenHandler creates a short URL answering as the Accept header prefers:
+// with a JSON link, with the short URL as plain text or with a HTML page.
+// Clients without preference get the HTML fragment of the first versions.
 func (c *URLShortener) shortenHandler(w http.ResponseWriter, r *http.Request) {
-	serverAddress := r.Host
+	w.Header().Add("Vary", "Accept")
+
+	mediaType := negotiate(r.Header.Get("Accept"), mediaTypeHTML, mediaTypeJSON, mediaTypeText)
+
+	writeError := writeTextError
+	if mediaType == mediaTypeJSON {
+		writeError = writeJSONError
+	}
+
+	if !c.allowRequest(w, r, c.createLimiter) {
+		writeError(w, http.StatusTooManyRequests, rateLimitedMessage)
+		return
+	}
+
 	url := r.URL
 	query := url.Query()
-	longURL := query.Get("url")
+	rawURL := query.Get("url")
+	alias := query.Get("alias")
+
+	expiresAt, err := parseExpiry(c.now(), query.Get("ttl"), query.Get("expires_at"))
+
+	var redirectCode int
+	if err == nil {
+		redirectCode, err = parseRedirectCode(query.Get("redirect_code"))
+	}
+
+	var interstitial bool
+	if err == nil {
+		interstitial, err = parseInterstitial(query.Get("interstitial"))
+	}
+
+	var shortURL string
+	var link Link
+
+	if err == nil {
+		shortURL, _, err = c.createShortURL(r, alias, Link{URL: rawURL, ExpiresAt: expiresAt, RedirectCode: redirectCode, Interstitial: interstitial})
+	}
+
+	if err == nil {
+		// the link stored may predate the request, when it is reused
+		link, err = c.GetLink(shortURL)
+	}
+
+	if err != nil {
+		writeError(w, shortenErrorStatus(err), err.Error())
+		return
+	}
+
+	shortened := c.newLinkJSON(r, shortURL, link)
+
+	switch mediaType {
+	case mediaTypeJSON:
+		writeJSON(w, http.StatusOK, shortened)
+	case mediaTypeText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, shortened.ShortURL)
+	case mediaTypeHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		shortenedPage.Execute(w, shortened)
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		shortenedFragment.Execute(w, shortened)
+	}
+}
+
+// createShortURL normalizes the long URL of the link received with r, owned
+// by the API key of r, and stores the link under the alias, when given, or
+// under a generated short URL. It returns the short URL and whether it
+// already held the link.
+func (c *URLShortener) createShortURL(r *http.Request, alias string, link Link) (string, bool, error) {
+	longURL, err := c.normalizeURL(r, link.URL)
+
+	if err != nil {
+		return "", false, err
+	}
 
-	shortURL := Shorten(longURL)
+	link.URL = longURL
+	link.Owner = ownerFrom(r.Context())
+
+	if alias != "" {
+		existing, err := c.aliasURL(alias, link)
+		return alias, existing, err
+	}
 
-	c.addURL(longURL, shortURL)
+	return c.shortenURL(link)
+}
 
-	linkAddress := fmt.Sprintf("http://%s", serverAddress)
-	hrefAddress := fmt.Sprintf("%s/%s", linkAddress, shortURL)
-	hrefText := fmt.Sprintf("%s -> %s", shortURL, longURL)
+// shortenErrorStatus maps the errors of a shorten request to status codes
+func shortenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidExpiry), errors.Is(err, ErrInvalidRedirectCode):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidInterstitial):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidAlias), errors.Is(err, ErrReservedAlias):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAliasTaken):
+		return http.StatusConflict
+	}
 
-	fmt.Fprintf(w, "<a href=\"%s\">%s</a>", hrefAddress, hrefText)
-	c.statistics.incrementHandlerCounter(ShortenHandlerIndex, true)
+	return http.StatusInternalServerError
 }
 
 func (c *URLShortener) statisticsHandler(w http.ResponseWriter, r *http.Request) {
@@ -115,35 +650,87 @@ func (c *URLShortener) statisticsHandler(w http.ResponseWriter, r *http.Request)
 	query := url.Query()
 	format := query.Get("format")
 
+	shortURL := strings.TrimPrefix(strings.TrimPrefix(url.Path, c.statisticsRoute), "/")
+
+	if shortURL != "" {
+		c.linkStatisticsHandler(w, r, shortURL, format)
+		return
+	}
+
 	if f := strings.ToLower(format); f == "json" {
 		jsonCandidate, err := json.Marshal(&c.statistics)
 
 		if err != nil {
 			w.WriteHeader(http.StatusNoContent)
-			c.statistics.incrementHandlerCounter(StatisticsHandlerIndex, false)
 			return
 		}
 
 		fmt.Fprintf(w, "%s", jsonCandidate)
-		c.statistics.incrementHandlerCounter(StatisticsHandlerIndex, true)
 		return
 	}
 
 	fmt.Fprintf(w, "%s", &c.statistics)
-	c.statistics.incrementHandlerCounter(StatisticsHandlerIndex, true)
+}
+
+// linkStatisticsHandler writes the click analytics of a short URL, links of
+// other API keys are not found
+func (c *URLShortener) linkStatisticsHandler(w http.ResponseWriter, r *http.Request, shortURL, format string) {
+	if link, err := c.store.Get(shortURL); err != nil || !c.isOwnedBy(r, link) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	stats := c.analytics.snapshot(shortURL)
+
+	if f := strings.ToLower(format); f == "json" {
+		jsonCandidate, err := json.Marshal(&stats)
+
+		if err != nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		fmt.Fprintf(w, "%s", jsonCandidate)
+		return
+	}
+
+	fmt.Fprintf(w, "%s", stats)
 }
 
 func (c *URLShortener) expanderHandler(w http.ResponseWriter, r *http.Request) {
+	if !c.allowRequest(w, r, c.redirectLimiter) {
+		http.Error(w, rateLimitedMessage, http.StatusTooManyRequests)
+		return
+	}
+
 	shortURLCandidate := r.URL.Path[len(c.expanderRoute):]
 
-	redirectURL, err := c.GetURL(shortURLCandidate)
+	link, err := c.GetLink(shortURLCandidate)
+
+	if errors.Is(err, ErrExpired) {
+		w.WriteHeader(http.StatusGone)
+		c.statistics.redirected(false)
+		return
+	}
 
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
-		c.statistics.incrementHandlerCounter(ExpanderHandlerIndex, false)
+		c.statistics.redirected(false)
 		return
 	}
 
-	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
-	c.statistics.incrementHandlerCounter(ExpanderHandlerIndex, true)
+	if !c.isRedirectAllowed(link.URL) {
+		http.Error(w, "destination not allowed", http.StatusForbidden)
+		c.statistics.redirected(false)
+		return
+	}
+
+	if link.Interstitial {
+		c.renderInterstitial(w, r, shortURLCandidate, link)
+	} else {
+		http.Redirect(w, r, c.destination(link, r), c.redirectCode(link))
+	}
+
+	c.analytics.record(shortURLCandidate, r, c.now())
+	c.statistics.redirected(true)
 }
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAddURL(t *testing.T) {
@@ -139,8 +140,12 @@ func TestStatisticsHandler(t *testing.T) {
 		t.Errorf("Incorrect TotalURL, got: %v, want: %v.", stats.ServerStats.TotalURL, 0)
 	}
 
-	if stats.ServerStats.Redirects.Success != 1 {
-		t.Errorf("Incorrect success, got: %v, want: %v.", stats.ServerStats.Redirects.Success, 1)
+	if stats.Version != StatsVersion {
+		t.Errorf("Incorrect version, got: %v, want: %v.", stats.Version, StatsVersion)
+	}
+
+	if stats.ServerStats.Redirects.Success != 0 {
+		t.Errorf("Incorrect success, got: %v, want: %v.", stats.ServerStats.Redirects.Success, 0)
 	}
 }
 
@@ -170,12 +175,100 @@ func TestShortenHandler(t *testing.T) {
 	}
 }
 
+func TestShortenHandlerNegotiation(t *testing.T) {
+	tests := []struct {
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{"text/plain", "text/plain; charset=utf-8", "http://localhost:9090/4611ce1\n"},
+		{"application/json", "application/json; charset=utf-8", `"short_url":"http://localhost:9090/4611ce1"`},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8", "<!DOCTYPE html>"},
+		{"application/json;q=0.5, text/plain", "text/plain; charset=utf-8", "http://localhost:9090/4611ce1\n"},
+		{"*/*", "text/html; charset=utf-8", "<a href=\"http://localhost:9090/4611ce1\">"},
+		{"image/png", "text/html; charset=utf-8", "<a href=\"http://localhost:9090/4611ce1\">"},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener()
+
+		request := httptest.NewRequest("GET", "/shorten?url=https://github.com/develersrl/powersoft-hmi", nil)
+		request.Host = "localhost:9090"
+		request.Header.Set("Accept", test.accept)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.shortenHandler(responseRecorder, request)
+
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Unexpected status code for %q, got: %v, want: %v.", test.accept, responseRecorder.Code, http.StatusOK)
+		}
+
+		if contentType := responseRecorder.Header().Get("Content-Type"); contentType != test.wantContentType {
+			t.Errorf("Incorrect content type for %q, got: %s, want: %s.", test.accept, contentType, test.wantContentType)
+		}
+
+		if vary := responseRecorder.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Incorrect Vary header, got: %s, want: %s.", vary, "Accept")
+		}
+
+		if body := responseRecorder.Body.String(); !strings.Contains(body, test.wantBody) {
+			t.Errorf("Incorrect body for %q, got: %s, want it containing: %s.", test.accept, body, test.wantBody)
+		}
+	}
+}
+
+func TestShortenHandlerJSON(t *testing.T) {
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+
+	request := httptest.NewRequest("GET", "/shorten?url=https://github.com/develersrl/powersoft-hmi", nil)
+	request.Host = "localhost:9090"
+	request.Header.Set("Accept", "application/json")
+	responseRecorder := httptest.NewRecorder()
+
+	sut.shortenHandler(responseRecorder, request)
+
+	var got linkJSON
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&got); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if got.Code != "4611ce1" || got.LongURL != "https://github.com/develersrl/powersoft-hmi" {
+		t.Errorf("Incorrect link, got: %v.", got)
+	}
+
+	want := time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	if got.CreatedAt == nil || !got.CreatedAt.Equal(want) {
+		t.Errorf("Incorrect creation time, got: %v, want: %v.", got.CreatedAt, want)
+	}
+
+	request = httptest.NewRequest("GET", "/shorten?url=ftp://example.com", nil)
+	request.Header.Set("Accept", "application/json")
+	responseRecorder = httptest.NewRecorder()
+
+	sut.shortenHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusBadRequest)
+	}
+
+	var gotError errorJSON
+	if err := json.NewDecoder(responseRecorder.Body).Decode(&gotError); err != nil || gotError.Error == "" {
+		t.Errorf("Expected a JSON error but got: %v, %v.", gotError, err)
+	}
+}
+
 func TestPersistTo(t *testing.T) {
 	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
 
 	const longURL = "https://github.com/develersrl/powersoft-hmi"
 	const shortURL = "4611ce1"
-	var want = fmt.Sprintf(`{"%s":"%s"}`, shortURL, longURL)
+	var want = fmt.Sprintf(`{"%s":{"url":"%s","created_at":"2020-09-08T10:00:00Z"}}`, shortURL, longURL)
 	var builder strings.Builder
 
 	sut.addURL(longURL, shortURL)
@@ -201,11 +294,239 @@ func TestUnpersistFrom(t *testing.T) {
 		t.Fatalf("Unexpected error but got: %s.", err)
 	}
 
-	if longURL != sut.mappings[shortURL] {
-		t.Errorf("Incorrect long URL value, got: %s, want: %s.", sut.mappings[shortURL], longURL)
+	gotLink, err := sut.store.Get(shortURL)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL != gotLink.URL {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", gotLink.URL, longURL)
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 1 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 1)
 	}
+}
+
+func TestWithStore(t *testing.T) {
+	store := NewMemoryStore()
+	store.Put("4611ce1", Link{URL: "https://github.com/develersrl/powersoft-hmi"})
+
+	sut := NewURLShortener(WithStore(store))
 
 	if sut.statistics.ServerStats.TotalURL != 1 {
 		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 1)
 	}
+
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	if _, err := store.Get("f495791"); err != nil {
+		t.Errorf("Expected URL added to the store but got: %s.", err)
+	}
+}
+
+func TestShortenURLCollision(t *testing.T) {
+	// every URL hashes to the same digest, unless re-salted
+	hasher := func(input string) string {
+		if strings.HasSuffix(input, "#1") {
+			return "bbbbbbbbbb"
+		}
+		return "aaaaaaaaaa"
+	}
+
+	sut := NewURLShortener(WithCodeGenerator(&HashGenerator{hasher}))
+
+	tests := []struct {
+		longURL      string
+		wantShortURL string
+	}{
+		{"https://a.example", "aaaaaaa"},
+		{"https://b.example", "aaaaaaaa"},
+		{"https://c.example", "aaaaaaaaa"},
+		{"https://d.example", "aaaaaaaaaa"},
+		{"https://e.example", "bbbbbbb"},
+		{"https://a.example", "aaaaaaa"},
+		{"https://c.example", "aaaaaaaaa"},
+		{"https://e.example", "bbbbbbb"},
+	}
+
+	for _, test := range tests {
+		shortURL, _, err := sut.shortenURL(Link{URL: test.longURL})
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if shortURL != test.wantShortURL {
+			t.Errorf("Incorrect short URL for %s, got: %s, want: %s.", test.longURL, shortURL, test.wantShortURL)
+		}
+
+		longURL, _ := sut.GetURL(shortURL)
+		if longURL != test.longURL {
+			t.Errorf("Incorrect long URL for %s, got: %s, want: %s.", shortURL, longURL, test.longURL)
+		}
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 5 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 5)
+	}
+}
+
+func TestShortenURLExhausted(t *testing.T) {
+	hasher := func(input string) string {
+		return "aaaaaaa"
+	}
+
+	sut := NewURLShortener(WithCodeGenerator(&HashGenerator{hasher}))
+
+	if _, _, err := sut.shortenURL(Link{URL: "https://a.example"}); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if _, _, err := sut.shortenURL(Link{URL: "https://b.example"}); err == nil {
+		t.Error("Expected error but got nil.")
+	}
+
+	if longURL, _ := sut.GetURL("aaaaaaa"); longURL != "https://a.example" {
+		t.Errorf("Existing mapping overwritten, got: %s, want: %s.", longURL, "https://a.example")
+	}
+}
+
+func TestShortenURLSkipsReservedCodes(t *testing.T) {
+	tests := []struct {
+		digest       string
+		wantShortURL string
+	}{
+		{"shortenxyz", "shortenx"},
+		{"abc.pngxyz", "abc.pngx"},
+		{"abc.svgxyz", "abc.svgx"},
+		{"abcdefgxyz", "abcdefg"},
+	}
+
+	for _, test := range tests {
+		digest := test.digest
+		hasher := func(input string) string {
+			return digest
+		}
+
+		sut := NewURLShortener(WithCodeGenerator(&HashGenerator{hasher}))
+
+		shortURL, _, err := sut.shortenURL(Link{URL: "https://a.example"})
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if shortURL != test.wantShortURL {
+			t.Errorf("Incorrect short URL for digest %s, got: %s, want: %s.", test.digest, shortURL, test.wantShortURL)
+		}
+	}
+}
+
+func TestShortenURLKeepsShortenCodes(t *testing.T) {
+	sut := NewURLShortener()
+
+	longURL := "https://github.com/develersrl/powersoft-hmi"
+
+	shortURL, _, err := sut.shortenURL(Link{URL: longURL})
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if shortURL != Shorten(longURL) {
+		t.Errorf("Incorrect short URL, got: %s, want: %s.", shortURL, Shorten(longURL))
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	sut := NewURLShortener(WithRoutePrefix("/s/"))
+	other := NewURLShortener()
+
+	request := httptest.NewRequest("GET", "/s/shorten?url=https://wttr.in/Florence", nil)
+	request.Host = "localhost:9090"
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
+
+	wantLink := "http://localhost:9090/s/f495791"
+	if body := responseRecorder.Body.String(); !strings.Contains(body, wantLink) {
+		t.Errorf("Missing short link %s in body: %s.", wantLink, body)
+	}
+
+	tests := []struct {
+		handler        http.Handler
+		path           string
+		wantStatusCode int
+	}{
+		{sut, "/s/f495791", http.StatusSeeOther},
+		{sut, "/f495791", http.StatusNotFound},
+		{sut, "/s/statistics", http.StatusOK},
+		{sut, "/s/api/v1/links/f495791", http.StatusOK},
+		{other, "/f495791", http.StatusNotFound},
+		{other, "/statistics", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		test.handler.ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.path, nil))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.path, responseRecorder.Code, test.wantStatusCode)
+		}
+	}
+}
+
+func TestRouteOptions(t *testing.T) {
+	sut := NewURLShortener(WithShortenRoute("/new"), WithStatisticsRoute("/stats"), WithExpanderRoute("/go"))
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	tests := []struct {
+		path           string
+		wantStatusCode int
+	}{
+		{"/new?url=https://wttr.in/Rome", http.StatusOK},
+		{"/stats", http.StatusOK},
+		{"/go/f495791", http.StatusSeeOther},
+		{"/f495791", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		sut.ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.path, nil))
+
+		if responseRecorder.Code != test.wantStatusCode {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", test.path, responseRecorder.Code, test.wantStatusCode)
+		}
+	}
+}
+
+func TestHandlerNamesFollowRoutes(t *testing.T) {
+	sut := NewURLShortener(WithRoutePrefix("/s/"), WithShortenRoute("/new"), WithExpanderRoute("/go"))
+
+	wantNames := []string{"/s/new", "/s/statistics", "/s/go/", "/s/api/v1/links", "/s/metrics", "/s/go/{code}.{png,svg}", "/s/go/{code}+"}
+
+	for i, handler := range sut.statistics.ServerStats.Handlers {
+		if handler.Name != wantNames[i] {
+			t.Errorf("Incorrect name of handler %d, got: %s, want: %s.", i, handler.Name, wantNames[i])
+		}
+	}
+}
+
+func TestSetupHandlerFunctionsTwice(t *testing.T) {
+	sut := NewURLShortener(WithRoutePrefix("/setup-twice"))
+
+	sut.SetupHandlerFunctions()
+	sut.SetupHandlerFunctions()
+
+	responseRecorder := httptest.NewRecorder()
+
+	http.DefaultServeMux.ServeHTTP(responseRecorder, httptest.NewRequest("GET", "/setup-twice/statistics", nil))
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusOK)
+	}
 }
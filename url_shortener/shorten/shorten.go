@@ -5,14 +5,22 @@ import (
 	"fmt"
 )
 
+// shortURLLength is the length of the short URLs returned by Shorten
+const shortURLLength = 7
+
 // Shorten function returns a unique shorten form for a URL
 func Shorten(longURL string) string {
+	shortURL := hexDigest(longURL)[:shortURLLength]
+
+	return shortURL
+}
+
+// hexDigest function returns the whole SHA-1 digest of a URL as hexadecimal
+func hexDigest(longURL string) string {
 	hasher := sha1.New()
 
 	hasher.Write([]byte(longURL))
 	sum := hasher.Sum(nil)
 
-	shortURL := fmt.Sprintf("%x", sum)[:7]
-
-	return shortURL
+	return fmt.Sprintf("%x", sum)
 }
@@ -0,0 +1,112 @@
+package shorten
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.count += int64(n)
+	return n, err
+}
+
+// SnapshotTo function persists the URL mappings to the file at path. The
+// snapshot is written to a temporary file in the same directory and renamed
+// into place, so a crash mid-write never leaves a truncated file at path.
+// When a write-ahead log is configured its records are folded in the
+// snapshot, so the log is truncated once the snapshot is in place.
+func (c *URLShortener) SnapshotTo(path string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	start := time.Now()
+
+	size, err := writeFileAtomically(path, c.PersistTo)
+	if err != nil {
+		c.statistics.snapshotFailed()
+		return err
+	}
+
+	if c.wal != nil {
+		if err := c.wal.truncate(); err != nil {
+			c.statistics.snapshotFailed()
+			return err
+		}
+	}
+
+	c.statistics.snapshotTaken(time.Since(start), size, start)
+	return nil
+}
+
+// writeFileAtomically replaces the file at path with what write writes,
+// going through a temporary file renamed into place. It returns the size of
+// the new file in bytes.
+func writeFileAtomically(path string, write func(w io.Writer) error) (int64, error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := ioutil.TempFile(dir, base+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+
+	tempPath := f.Name()
+	defer os.Remove(tempPath) // no-op once renamed
+	defer f.Close()
+
+	counter := &countingWriter{w: f}
+	writer := bufio.NewWriter(counter)
+
+	if err := write(writer); err != nil {
+		return 0, err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		return 0, err
+	}
+
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		return 0, err
+	}
+
+	syncDir(dir)
+
+	return counter.count, nil
+}
+
+// syncDir makes a rename in dir durable, it is best effort as not every
+// platform allows syncing a directory
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+
+	d.Sync()
+}
@@ -0,0 +1,81 @@
+package shorten
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSnapshotTo(t *testing.T) {
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "persistence.json")
+
+	if err := ioutil.WriteFile(snapshotPath, []byte(`{"f495791":"https://wttr.in/Florence"}`), 0644); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	sut := NewURLShortener()
+	sut.now = func() time.Time {
+		return time.Date(2020, 9, 8, 10, 0, 0, 0, time.UTC)
+	}
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	if err := sut.SnapshotTo(snapshotPath); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	want := `{"4611ce1":{"url":"https://github.com/develersrl/powersoft-hmi","created_at":"2020-09-08T10:00:00Z"}}` + "\n"
+	got, _ := ioutil.ReadFile(snapshotPath)
+	if string(got) != want {
+		t.Errorf("Incorrect snapshot, got: %s, want: %s.", got, want)
+	}
+
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Incorrect number of files after snapshot, got: %v, want: %v.", len(entries), 1)
+	}
+
+	snapshots := sut.statistics.ServerStats.Snapshots
+	if snapshots.Count != 1 {
+		t.Errorf("Incorrect snapshot count, got: %v, want: %v.", snapshots.Count, 1)
+	}
+
+	if snapshots.LastSizeBytes != int64(len(want)) {
+		t.Errorf("Incorrect snapshot size, got: %v, want: %v.", snapshots.LastSizeBytes, len(want))
+	}
+
+	if snapshots.LastUnixTime == 0 {
+		t.Error("Expected last snapshot time to be set.")
+	}
+}
+
+func TestSnapshotToFailureKeepsPreviousFile(t *testing.T) {
+	dir := t.TempDir()
+	snapshotPath := filepath.Join(dir, "persistence.json")
+
+	// a directory in place of the snapshot makes the rename fail
+	if err := os.Mkdir(snapshotPath, 0755); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(snapshotPath, "keep"), nil, 0644); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	sut := NewURLShortener()
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	if err := sut.SnapshotTo(snapshotPath); err == nil {
+		t.Fatal("Expected error but got nil.")
+	}
+
+	if sut.statistics.ServerStats.Snapshots.Failed != 1 {
+		t.Errorf("Incorrect failed snapshot count, got: %v, want: %v.", sut.statistics.ServerStats.Snapshots.Failed, 1)
+	}
+
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary snapshot to be removed, got %v file(s).", len(entries))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync/atomic"
+	"time"
 )
 
 // HandlerIndex an index for handlers
@@ -14,17 +15,30 @@ const (
 	ShortenHandlerIndex HandlerIndex = iota
 	StatisticsHandlerIndex
 	ExpanderHandlerIndex
+	APIHandlerIndex
+	MetricsHandlerIndex
+	QRCodeHandlerIndex
+	PreviewHandlerIndex
 )
 
+// StatsVersion the version of the StatsJSON model. Version 2 counts
+// redirects only on the expander and adds per handler responses and latency.
+const StatsVersion = 2
+
 // StatsJSON Statistic data ready for JSON serialization
 type StatsJSON struct {
+	Version     int             `json:"version"`
 	ServerStats serverStatsJSON `json:"server_stats"`
+
+	redirectLatency *histogram
 }
 
 type serverStatsJSON struct {
-	TotalURL  int64         `json:"total_url"`
-	Redirects redirectsJSON `json:"redirects"`
-	Handlers  []handlerJSON `json:"handlers"`
+	TotalURL    int64           `json:"total_url"`
+	Redirects   redirectsJSON   `json:"redirects"`
+	Handlers    []handlerJSON   `json:"handlers"`
+	Snapshots   snapshotsJSON   `json:"snapshots"`
+	RateLimited rateLimitedJSON `json:"rate_limited"`
 }
 
 type redirectsJSON struct {
@@ -32,16 +46,52 @@ type redirectsJSON struct {
 	Failed  int64 `json:"failed"`
 }
 
+// rateLimitedJSON requests refused by the rate limits and the limits
+// configured, nil when disabled
+type rateLimitedJSON struct {
+	Create        int64      `json:"create"`
+	Redirect      int64      `json:"redirect"`
+	CreateLimit   *RateLimit `json:"create_limit,omitempty"`
+	RedirectLimit *RateLimit `json:"redirect_limit,omitempty"`
+}
+
+type snapshotsJSON struct {
+	Count          int64 `json:"count"`
+	Failed         int64 `json:"failed"`
+	LastDurationNs int64 `json:"last_duration_ns"`
+	LastSizeBytes  int64 `json:"last_size_bytes"`
+	LastUnixTime   int64 `json:"last_unix_time"`
+}
+
 type handlerJSON struct {
-	Name  string `json:"name"`
-	Count int64  `json:"count"`
-	index HandlerIndex
+	Name      string        `json:"name"`
+	Count     int64         `json:"count"`
+	Responses responsesJSON `json:"responses"`
+	Latency   latencyJSON   `json:"latency"`
+	index     HandlerIndex
+
+	statuses *statusCounters
+}
+
+// responsesJSON responses of a handler by status class
+type responsesJSON struct {
+	Status2xx int64 `json:"2xx"`
+	Status3xx int64 `json:"3xx"`
+	Status4xx int64 `json:"4xx"`
+	Status5xx int64 `json:"5xx"`
+}
+
+type latencyJSON struct {
+	TotalNs int64 `json:"total_ns"`
+	MaxNs   int64 `json:"max_ns"`
 }
 
 // NewStatsJSON a StatsJSON constructor
 func NewStatsJSON() StatsJSON {
 	statsJSON := StatsJSON{}
 
+	statsJSON.Version = StatsVersion
+
 	stats := &statsJSON.ServerStats
 
 	stats.TotalURL = 0
@@ -52,17 +102,38 @@ func NewStatsJSON() StatsJSON {
 
 	handlers := &stats.Handlers
 	*handlers = make([]handlerJSON, 0)
-	*handlers = append(*handlers, handlerJSON{"/shorten/", 0, ShortenHandlerIndex})
-	*handlers = append(*handlers, handlerJSON{"/statistics", 0, StatisticsHandlerIndex})
-	*handlers = append(*handlers, handlerJSON{"/", 0, ExpanderHandlerIndex})
+	*handlers = append(*handlers, newHandlerJSON("/shorten/", ShortenHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/statistics", StatisticsHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/", ExpanderHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/api/v1/links", APIHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/metrics", MetricsHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/{code}.{png,svg}", QRCodeHandlerIndex))
+	*handlers = append(*handlers, newHandlerJSON("/{code}+", PreviewHandlerIndex))
+
+	statsJSON.redirectLatency = newHistogram(redirectBuckets)
 
 	return statsJSON
 }
 
+// nameHandler renames a handler, as its route is configurable
+func (s *StatsJSON) nameHandler(index HandlerIndex, name string) {
+	s.ServerStats.Handlers[index].Name = name
+}
+
+func newHandlerJSON(name string, index HandlerIndex) handlerJSON {
+	handler := handlerJSON{}
+
+	handler.Name = name
+	handler.index = index
+	handler.statuses = newStatusCounters()
+
+	return handler
+}
+
 func (s *StatsJSON) String() string {
 	statsBody := &strings.Builder{}
 
-	stats := s.ServerStats
+	stats := &s.ServerStats
 
 	statsBody.WriteString("Some statistics:\n\n")
 	totalURL := atomic.LoadInt64(&stats.TotalURL)
@@ -75,10 +146,47 @@ func (s *StatsJSON) String() string {
 	fmt.Fprintf(statsBody, "Failed redirects: %v\n", failed)
 
 	handlers := &stats.Handlers
-	for _, handler := range *handlers {
+	for i := range *handlers {
+		handler := &(*handlers)[i]
 		name := handler.Name
 		count := atomic.LoadInt64(&handler.Count)
 		fmt.Fprintf(statsBody, "Handler %s called %v time(s)\n", name, count)
+		if count == 0 {
+			continue
+		}
+
+		responses := &handler.Responses
+		fmt.Fprintf(statsBody, "  responses 2xx: %v, 3xx: %v, 4xx: %v, 5xx: %v\n",
+			atomic.LoadInt64(&responses.Status2xx), atomic.LoadInt64(&responses.Status3xx),
+			atomic.LoadInt64(&responses.Status4xx), atomic.LoadInt64(&responses.Status5xx))
+		latency := &handler.Latency
+		mean := time.Duration(atomic.LoadInt64(&latency.TotalNs) / count)
+		max := time.Duration(atomic.LoadInt64(&latency.MaxNs))
+		fmt.Fprintf(statsBody, "  latency mean: %s, max: %s\n", mean, max)
+	}
+
+	rateLimited := &stats.RateLimited
+	fmt.Fprintf(statsBody, "Rate limited creations: %v\n", atomic.LoadInt64(&rateLimited.Create))
+	if limit := rateLimited.CreateLimit; limit != nil {
+		fmt.Fprintf(statsBody, "  limit: %v/s, burst: %v\n", limit.Rate, limit.Burst)
+	}
+	fmt.Fprintf(statsBody, "Rate limited redirects: %v\n", atomic.LoadInt64(&rateLimited.Redirect))
+	if limit := rateLimited.RedirectLimit; limit != nil {
+		fmt.Fprintf(statsBody, "  limit: %v/s, burst: %v\n", limit.Rate, limit.Burst)
+	}
+
+	snapshots := &stats.Snapshots
+	snapshotCount := atomic.LoadInt64(&snapshots.Count)
+	fmt.Fprintf(statsBody, "Snapshots taken: %v\n", snapshotCount)
+	snapshotFailed := atomic.LoadInt64(&snapshots.Failed)
+	fmt.Fprintf(statsBody, "Snapshots failed: %v\n", snapshotFailed)
+	if snapshotCount > 0 {
+		lastTime := time.Unix(atomic.LoadInt64(&snapshots.LastUnixTime), 0)
+		fmt.Fprintf(statsBody, "Last snapshot at: %s\n", lastTime.Format(time.RFC3339))
+		lastDuration := time.Duration(atomic.LoadInt64(&snapshots.LastDurationNs))
+		fmt.Fprintf(statsBody, "Last snapshot duration: %s\n", lastDuration)
+		lastSize := atomic.LoadInt64(&snapshots.LastSizeBytes)
+		fmt.Fprintf(statsBody, "Last snapshot size: %v byte(s)\n", lastSize)
 	}
 
 	return statsBody.String()
@@ -90,25 +198,117 @@ func (s *StatsJSON) updateTotalURL(totalURL int64) {
 	atomic.StoreInt64(&stats.TotalURL, totalURL)
 }
 
-func (s *StatsJSON) incrementHandlerCounter(handlerIndex HandlerIndex, succeeded bool) {
+// observeResponse accounts a response of a handler by status code and
+// latency, the duration of the expander responses is also accounted in the
+// redirect latency histogram
+func (s *StatsJSON) observeResponse(handlerIndex HandlerIndex, status int, duration time.Duration) {
 	stats := &s.ServerStats
 
 	handlers := &stats.Handlers
 	for i := range *handlers {
 		handler := &(*handlers)[i]
-		index := handler.index
-		if index != handlerIndex {
+		if handler.index != handlerIndex {
 			continue
 		}
 
-		atomic.AddInt64(&handler.Count, 1)
+		handler.observe(status, duration)
 		break
 	}
 
-	redirects := &stats.Redirects
+	if handlerIndex == ExpanderHandlerIndex {
+		s.redirectLatency.observe(duration)
+	}
+}
+
+func (h *handlerJSON) observe(status int, duration time.Duration) {
+	responses := &h.Responses
+	switch {
+	case status >= 500:
+		atomic.AddInt64(&responses.Status5xx, 1)
+	case status >= 400:
+		atomic.AddInt64(&responses.Status4xx, 1)
+	case status >= 300:
+		atomic.AddInt64(&responses.Status3xx, 1)
+	case status >= 200:
+		atomic.AddInt64(&responses.Status2xx, 1)
+	}
+
+	h.statuses.increment(status)
+
+	latency := &h.Latency
+	atomic.AddInt64(&latency.TotalNs, int64(duration))
+	for {
+		max := atomic.LoadInt64(&latency.MaxNs)
+		if int64(duration) <= max || atomic.CompareAndSwapInt64(&latency.MaxNs, max, int64(duration)) {
+			break
+		}
+	}
+
+	atomic.AddInt64(&h.Count, 1)
+}
+
+// redirected accounts a redirect of the expander
+func (s *StatsJSON) redirected(succeeded bool) {
+	redirects := &s.ServerStats.Redirects
+
 	if succeeded {
 		atomic.AddInt64(&redirects.Success, 1)
 	} else {
 		atomic.AddInt64(&redirects.Failed, 1)
 	}
 }
+
+// setRateLimits records the limits of the rate limiters, nil when disabled
+func (s *StatsJSON) setRateLimits(create, redirect *rateLimiter) {
+	rateLimited := &s.ServerStats.RateLimited
+
+	if create != nil {
+		limit := create.limit
+		rateLimited.CreateLimit = &limit
+	}
+
+	if redirect != nil {
+		limit := redirect.limit
+		rateLimited.RedirectLimit = &limit
+	}
+}
+
+// rateLimited accounts a request refused by a rate limit
+func (s *StatsJSON) rateLimited(kind limitKind) {
+	rateLimited := &s.ServerStats.RateLimited
+
+	switch kind {
+	case createLimit:
+		atomic.AddInt64(&rateLimited.Create, 1)
+	case redirectLimit:
+		atomic.AddInt64(&rateLimited.Redirect, 1)
+	}
+}
+
+func (s *StatsJSON) snapshotTaken(duration time.Duration, size int64, at time.Time) {
+	snapshots := &s.ServerStats.Snapshots
+
+	atomic.StoreInt64(&snapshots.LastDurationNs, int64(duration))
+	atomic.StoreInt64(&snapshots.LastSizeBytes, size)
+	atomic.StoreInt64(&snapshots.LastUnixTime, at.Unix())
+	atomic.AddInt64(&snapshots.Count, 1)
+}
+
+func (s *StatsJSON) snapshotFailed() {
+	snapshots := &s.ServerStats.Snapshots
+
+	atomic.AddInt64(&snapshots.Failed, 1)
+}
+
+// lastSnapshot returns the snapshot statistics
+func (s *StatsJSON) lastSnapshot() snapshotsJSON {
+	snapshots := &s.ServerStats.Snapshots
+
+	return snapshotsJSON{
+		Count:          atomic.LoadInt64(&snapshots.Count),
+		Failed:         atomic.LoadInt64(&snapshots.Failed),
+		LastDurationNs: atomic.LoadInt64(&snapshots.LastDurationNs),
+		LastSizeBytes:  atomic.LoadInt64(&snapshots.LastSizeBytes),
+		LastUnixTime:   atomic.LoadInt64(&snapshots.LastUnixTime),
+	}
+}
@@ -1,6 +1,10 @@
 package shorten
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+	"time"
+)
 
 func TestStatistics(t *testing.T) {
 	sut := NewStatsJSON()
@@ -42,27 +46,67 @@ func TestUpdateTotalURL(t *testing.T) {
 	}
 }
 
-func TestIncrementHandlerCount(t *testing.T) {
+func TestObserveResponse(t *testing.T) {
 	tests := []struct {
 		handlerIndex     HandlerIndex
-		success          bool
+		status           int
+		duration         time.Duration
 		wantHandlerCount int64
-		wantSuccess      int64
-		wantFailed       int64
+		wantResponses    responsesJSON
+		wantMaxNs        int64
 	}{
-		{ShortenHandlerIndex, true, 1, 1, 0},
-		{ShortenHandlerIndex, false, 2, 1, 1},
-		{StatisticsHandlerIndex, true, 1, 2, 1},
-		{ExpanderHandlerIndex, true, 1, 3, 1},
-		{ExpanderHandlerIndex, false, 2, 3, 2},
-		{ExpanderHandlerIndex, false, 3, 3, 3},
-		{ExpanderHandlerIndex, false, 4, 3, 4},
+		{ShortenHandlerIndex, http.StatusOK, time.Millisecond, 1, responsesJSON{1, 0, 0, 0}, int64(time.Millisecond)},
+		{ShortenHandlerIndex, http.StatusBadRequest, 3 * time.Millisecond, 2, responsesJSON{1, 0, 1, 0}, int64(3 * time.Millisecond)},
+		{ShortenHandlerIndex, http.StatusInternalServerError, 2 * time.Millisecond, 3, responsesJSON{1, 0, 1, 1}, int64(3 * time.Millisecond)},
+		{ExpanderHandlerIndex, http.StatusSeeOther, time.Millisecond, 1, responsesJSON{0, 1, 0, 0}, int64(time.Millisecond)},
+		{ExpanderHandlerIndex, http.StatusNotFound, time.Millisecond, 2, responsesJSON{0, 1, 1, 0}, int64(time.Millisecond)},
 	}
 
 	sut := NewStatsJSON()
 
 	for _, test := range tests {
-		sut.incrementHandlerCounter(test.handlerIndex, test.success)
+		sut.observeResponse(test.handlerIndex, test.status, test.duration)
+
+		handler := sut.ServerStats.Handlers[test.handlerIndex]
+
+		if handler.Count != test.wantHandlerCount {
+			t.Errorf("Incorrect count value, got: %v, want: %v.", handler.Count, test.wantHandlerCount)
+		}
+
+		if handler.Responses != test.wantResponses {
+			t.Errorf("Incorrect responses value, got: %v, want: %v.", handler.Responses, test.wantResponses)
+		}
+
+		if handler.Latency.MaxNs != test.wantMaxNs {
+			t.Errorf("Incorrect max latency value, got: %v, want: %v.", handler.Latency.MaxNs, test.wantMaxNs)
+		}
+	}
+
+	if sut.ServerStats.Redirects != (redirectsJSON{}) {
+		t.Errorf("Incorrect redirects value, got: %v, want: %v.", sut.ServerStats.Redirects, redirectsJSON{})
+	}
+
+	if sut.redirectLatency.count != 2 {
+		t.Errorf("Incorrect redirect latency count, got: %v, want: %v.", sut.redirectLatency.count, 2)
+	}
+}
+
+func TestRedirected(t *testing.T) {
+	tests := []struct {
+		success     bool
+		wantSuccess int64
+		wantFailed  int64
+	}{
+		{true, 1, 0},
+		{false, 1, 1},
+		{false, 1, 2},
+		{true, 2, 2},
+	}
+
+	sut := NewStatsJSON()
+
+	for _, test := range tests {
+		sut.redirected(test.success)
 
 		if sut.ServerStats.Redirects.Success != test.wantSuccess {
 			t.Errorf("Incorrect success value, got: %v, want: %v.", sut.ServerStats.Redirects.Success, test.wantSuccess)
@@ -71,17 +115,5 @@ func TestIncrementHandlerCount(t *testing.T) {
 		if sut.ServerStats.Redirects.Failed != test.wantFailed {
 			t.Errorf("Incorrect failed value, got: %v, want: %v.", sut.ServerStats.Redirects.Failed, test.wantFailed)
 		}
-
-		handlers := &sut.ServerStats.Handlers
-		for i, handler := range *handlers {
-			index := handler.index
-			if index != test.handlerIndex {
-				continue
-			}
-
-			if sut.ServerStats.Handlers[i].Count != test.wantHandlerCount {
-				t.Errorf("Incorrect count value, got: %v, want: %v.", sut.ServerStats.Handlers[i].Count, test.wantHandlerCount)
-			}
-		}
 	}
 }
@@ -0,0 +1,96 @@
+package shorten
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNotFound is returned when a short URL has no mapping
+var ErrNotFound = errors.New("short URL not found")
+
+// Store is a storage backend for the mappings of short URLs to links
+type Store interface {
+	// Get returns the link mapped to shortURL or ErrNotFound
+	Get(shortURL string) (Link, error)
+	// Put maps shortURL to link, replacing any previous mapping
+	Put(shortURL string, link Link) error
+	// Delete removes the mapping of shortURL, if any
+	Delete(shortURL string) error
+	// Iterate calls fn for each mapping until fn returns false
+	Iterate(fn func(shortURL string, link Link) bool) error
+	// Len returns the number of mappings
+	Len() (int, error)
+}
+
+// MemoryStore an in-memory Store backed by a map, it is the default Store
+type MemoryStore struct {
+	mappings map[string]Link
+
+	mux sync.RWMutex
+}
+
+// NewMemoryStore a MemoryStore constructor
+func NewMemoryStore() *MemoryStore {
+	memoryStore := MemoryStore{}
+
+	memoryStore.mappings = make(map[string]Link)
+
+	return &memoryStore
+}
+
+// Get returns the link mapped to shortURL or ErrNotFound
+func (s *MemoryStore) Get(shortURL string) (Link, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	link, ok := s.mappings[shortURL]
+
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	return link, nil
+}
+
+// Put maps shortURL to link, replacing any previous mapping
+func (s *MemoryStore) Put(shortURL string, link Link) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.mappings[shortURL] = link
+
+	return nil
+}
+
+// Delete removes the mapping of shortURL, if any
+func (s *MemoryStore) Delete(shortURL string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	delete(s.mappings, shortURL)
+
+	return nil
+}
+
+// Iterate calls fn for each mapping until fn returns false, the store is
+// read locked during the iteration so fn must not modify it
+func (s *MemoryStore) Iterate(fn func(shortURL string, link Link) bool) error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	for shortURL, link := range s.mappings {
+		if !fn(shortURL, link) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of mappings
+func (s *MemoryStore) Len() (int, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return len(s.mappings), nil
+}
@@ -0,0 +1,72 @@
+package shorten
+
+import "testing"
+
+func TestMemoryStore(t *testing.T) {
+	sut := NewMemoryStore()
+
+	tests := []struct {
+		shortURL    string
+		longURL     string
+		wantTotal   int
+		wantLongURL string
+	}{
+		{"a", "https://a.example", 1, "https://a.example"},
+		{"b", "https://b.example", 2, "https://b.example"},
+		{"a", "https://c.example", 2, "https://c.example"},
+	}
+
+	for _, test := range tests {
+		if err := sut.Put(test.shortURL, Link{URL: test.longURL}); err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		link, err := sut.Get(test.shortURL)
+		if err != nil {
+			t.Fatalf("Unexpected error but got: %s.", err)
+		}
+
+		if link.URL != test.wantLongURL {
+			t.Errorf("Incorrect long URL value, got: %s, want: %s.", link.URL, test.wantLongURL)
+		}
+
+		if total, _ := sut.Len(); total != test.wantTotal {
+			t.Errorf("Incorrect total value, got: %v, want: %v.", total, test.wantTotal)
+		}
+	}
+
+	if err := sut.Delete("a"); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if _, err := sut.Get("a"); err != ErrNotFound {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreIterate(t *testing.T) {
+	sut := NewMemoryStore()
+	sut.Put("a", Link{URL: "https://a.example"})
+	sut.Put("b", Link{URL: "https://b.example"})
+	sut.Put("c", Link{URL: "https://c.example"})
+
+	visited := 0
+	sut.Iterate(func(shortURL string, link Link) bool {
+		visited++
+		return true
+	})
+
+	if visited != 3 {
+		t.Errorf("Incorrect visited value, got: %v, want: %v.", visited, 3)
+	}
+
+	visited = 0
+	sut.Iterate(func(shortURL string, link Link) bool {
+		visited++
+		return false
+	})
+
+	if visited != 1 {
+		t.Errorf("Incorrect visited value on early stop, got: %v, want: %v.", visited, 1)
+	}
+}
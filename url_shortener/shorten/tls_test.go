@@ -0,0 +1,100 @@
+package shorten
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSelfSignedCertificate(t *testing.T) {
+	certificate, err := SelfSignedCertificate([]string{"localhost", "127.0.0.1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if err := parsed.VerifyHostname("localhost"); err != nil {
+		t.Errorf("Unexpected error but got: %s.", err)
+	}
+
+	if err := parsed.VerifyHostname("127.0.0.1"); err != nil {
+		t.Errorf("Unexpected error but got: %s.", err)
+	}
+
+	if !parsed.NotAfter.Before(time.Now().Add(time.Hour)) {
+		t.Errorf("Incorrect expiry, got: %v, want before: %v.", parsed.NotAfter, time.Now().Add(time.Hour))
+	}
+
+	sut := NewURLShortener()
+
+	server := httptest.NewUnstartedServer(sut)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+
+	response, err := client.Get(server.URL + "/api/v1/links")
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", response.StatusCode, http.StatusOK)
+	}
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	tests := []struct {
+		httpsPort    string
+		target       string
+		wantLocation string
+	}{
+		{"443", "http://short.example/f495791?x=1", "https://short.example/f495791?x=1"},
+		{"", "http://short.example:8080/f495791", "https://short.example/f495791"},
+		{"9443", "http://short.example:8080/statistics", "https://short.example:9443/statistics"},
+		{"8443", "http://[::1]/f495791", "https://[::1]:8443/f495791"},
+		{"8443", "http://[::1]:8080/f495791", "https://[::1]:8443/f495791"},
+		{"443", "http://[2001:db8::1]:8080/f495791", "https://[2001:db8::1]/f495791"},
+		{"", "http://[::1]/f495791", "https://[::1]/f495791"},
+	}
+
+	for _, test := range tests {
+		responseRecorder := httptest.NewRecorder()
+
+		HTTPSRedirectHandler(test.httpsPort).ServeHTTP(responseRecorder, httptest.NewRequest("GET", test.target, nil))
+
+		if responseRecorder.Code != http.StatusPermanentRedirect {
+			t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusPermanentRedirect)
+		}
+
+		if location := responseRecorder.Header().Get("Location"); location != test.wantLocation {
+			t.Errorf("Incorrect location, got: %s, want: %s.", location, test.wantLocation)
+		}
+	}
+}
+
+func TestShortLinkScheme(t *testing.T) {
+	sut := NewURLShortener()
+
+	request := httptest.NewRequest("GET", "https://short.example/shorten?url=https://wttr.in/Florence", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.ServeHTTP(responseRecorder, request)
+
+	if body := responseRecorder.Body.String(); !strings.Contains(body, `href="https://short.example/f495791"`) {
+		t.Errorf("Incorrect short link scheme in body: %s.", body)
+	}
+}
@@ -0,0 +1,127 @@
+package shorten
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidURL is returned when a long URL cannot be shortened
+var ErrInvalidURL = errors.New("invalid URL")
+
+// maxURLLength bounds the long URLs, so their records always fit in the
+// write-ahead log
+const maxURLLength = 8 * 1024
+
+// defaultPorts are dropped from the hosts of normalized URLs
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// normalizeHost lowercases a host and drops the port when it is the default
+// one for the scheme
+func normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return host
+	}
+
+	if port != "" && port != defaultPorts[scheme] {
+		return host
+	}
+
+	if strings.Contains(hostname, ":") {
+		return "[" + hostname + "]" // IPv6 literal
+	}
+
+	return hostname
+}
+
+// isSchemeAllowed tells if the scheme is in the allowlist
+func (c *URLShortener) isSchemeAllowed(scheme string) bool {
+	for _, allowed := range c.allowedSchemes {
+		if strings.EqualFold(scheme, allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSelfHost tells if a normalized host points back at the shortener, either
+// as reached by r or as configured. r is nil for links not received with a
+// request, as the imported ones.
+func (c *URLShortener) isSelfHost(r *http.Request, host string) bool {
+	selfHosts := c.selfHosts
+	if r != nil {
+		_, requestHost := c.requestHost(r)
+		selfHosts = append([]string{r.Host, requestHost}, c.selfHosts...)
+	}
+
+	for _, selfHost := range selfHosts {
+		// the scheme the shortener is reached with is unknown, both
+		// default ports are dropped
+		if host == normalizeHost("http", normalizeHost("https", selfHost)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeURL validates the long URL received with r, nil for imported
+// links, and returns it in its normalized form, so equivalent URLs are
+// shortened to the same short URL
+func (c *URLShortener) normalizeURL(r *http.Request, rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: missing URL", ErrInvalidURL)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidURL, err)
+	}
+
+	if !u.IsAbs() || u.Host == "" {
+		return "", fmt.Errorf("%w: absolute URL required: %s", ErrInvalidURL, rawURL)
+	}
+
+	if !c.isSchemeAllowed(u.Scheme) {
+		return "", fmt.Errorf("%w: scheme not allowed: %s", ErrInvalidURL, u.Scheme)
+	}
+
+	u.Host = normalizeHost(u.Scheme, u.Host)
+
+	if c.isSelfHost(r, u.Host) {
+		return "", fmt.Errorf("%w: URL points back at the shortener: %s", ErrInvalidURL, rawURL)
+	}
+
+	if c.sortQuery && u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	normalized := u.String()
+
+	if len(normalized) > maxURLLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidURL, maxURLLength)
+	}
+
+	return normalized, nil
+}
+
+// isRedirectAllowed tells if a stored long URL can be redirected to, so
+// mappings loaded from old persistence files are checked too
+func (c *URLShortener) isRedirectAllowed(longURL string) bool {
+	u, err := url.Parse(longURL)
+	if err != nil {
+		return false
+	}
+
+	return u.IsAbs() && c.isSchemeAllowed(u.Scheme)
+}
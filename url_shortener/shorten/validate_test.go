@@ -0,0 +1,106 @@
+package shorten
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	sut := NewURLShortener(WithSelfHosts("sho.rt"))
+
+	tests := []struct {
+		rawURL  string
+		wantURL string
+		wantErr bool
+	}{
+		{"https://github.com/develersrl/powersoft-hmi", "https://github.com/develersrl/powersoft-hmi", false},
+		{"HTTPS://GitHub.COM/develersrl/powersoft-hmi", "https://github.com/develersrl/powersoft-hmi", false},
+		{"https://github.com:443/develersrl", "https://github.com/develersrl", false},
+		{"http://github.com:80/develersrl", "http://github.com/develersrl", false},
+		{"http://github.com:443/develersrl", "http://github.com:443/develersrl", false},
+		{"http://[::1]:80/x", "http://[::1]/x", false},
+		{"https://wttr.in/Rome?b=2&a=1", "https://wttr.in/Rome?b=2&a=1", false},
+		{"", "", true},
+		{"javascript:alert(1)", "", true},
+		{"ftp://files.example/a", "", true},
+		{"/relative/path", "", true},
+		{"https:///no-host", "", true},
+		{"http://localhost:9090/4611ce1", "", true},
+		{"https://sho.rt/4611ce1", "", true},
+		{"https://SHO.RT:443/4611ce1", "", true},
+		{"%zz", "", true},
+		{"https://wttr.in/" + strings.Repeat("a", maxURLLength), "", true},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "/shorten", nil)
+		request.Host = "localhost:9090"
+
+		got, err := sut.normalizeURL(request, test.rawURL)
+
+		if !test.wantErr && err != nil {
+			t.Errorf("Unexpected error for %s but got: %s.", test.rawURL, err)
+		}
+
+		if test.wantErr && !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("Incorrect error for %s, got: %v, want: %v.", test.rawURL, err, ErrInvalidURL)
+		}
+
+		if got != test.wantURL {
+			t.Errorf("Incorrect normalized URL, got: %s, want: %s.", got, test.wantURL)
+		}
+	}
+}
+
+func TestNormalizeURLSortedQuery(t *testing.T) {
+	sut := NewURLShortener(WithSortedQuery())
+
+	request := httptest.NewRequest("GET", "/shorten", nil)
+
+	first, _ := sut.normalizeURL(request, "https://wttr.in/Rome?b=2&a=1")
+	second, _ := sut.normalizeURL(request, "https://WTTR.in:443/Rome?a=1&b=2")
+
+	if first != "https://wttr.in/Rome?a=1&b=2" {
+		t.Errorf("Incorrect normalized URL, got: %s, want: %s.", first, "https://wttr.in/Rome?a=1&b=2")
+	}
+
+	if first != second {
+		t.Errorf("Equivalent URLs normalized differently, got: %s and %s.", first, second)
+	}
+}
+
+func TestShortenHandlerInvalidURL(t *testing.T) {
+	sut := NewURLShortener()
+
+	for _, query := range []string{"", "url=", "url=javascript:alert(1)", "url=/relative"} {
+		request := httptest.NewRequest("GET", "/shorten?"+query, nil)
+		responseRecorder := httptest.NewRecorder()
+
+		sut.shortenHandler(responseRecorder, request)
+
+		if responseRecorder.Code != http.StatusBadRequest {
+			t.Errorf("Unexpected status code for %s, got: %v, want: %v.", query, responseRecorder.Code, http.StatusBadRequest)
+		}
+	}
+
+	if sut.statistics.ServerStats.TotalURL != 0 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", sut.statistics.ServerStats.TotalURL, 0)
+	}
+}
+
+func TestExpanderHandlerRefusesDisallowedScheme(t *testing.T) {
+	sut := NewURLShortener()
+	sut.addURL("javascript:alert(1)", "evil")
+
+	request := httptest.NewRequest("GET", "/evil", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	sut.expanderHandler(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusForbidden {
+		t.Errorf("Unexpected status code, got: %v, want: %v.", responseRecorder.Code, http.StatusForbidden)
+	}
+}
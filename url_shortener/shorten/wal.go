@@ -0,0 +1,162 @@
+package shorten
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	walOpPut    = "put"
+	walOpDelete = "delete"
+
+	// maxWALRecordSize bounds a single log line, long URLs included
+	maxWALRecordSize = 1024 * 1024
+)
+
+// errWALRecordTooLarge is returned writing a record the log could not be
+// read back with
+var errWALRecordTooLarge = errors.New("WAL record too large")
+
+// WAL an append-only write-ahead log of the changes to the URL mappings, one
+// JSON record per line
+type WAL struct {
+	file *os.File
+
+	mux sync.Mutex
+}
+
+type walRecord struct {
+	Op       string `json:"op"`
+	ShortURL string `json:"short_url"`
+	Link     *Link  `json:"link,omitempty"`
+	// LongURL is only read back from logs written before links had metadata
+	LongURL string `json:"long_url,omitempty"`
+}
+
+// link returns the link carried by a put record
+func (r *walRecord) link() Link {
+	if r.Link != nil {
+		return *r.Link
+	}
+
+	return Link{URL: r.LongURL}
+}
+
+// OpenWAL opens, or creates, the write-ahead log at path for appending
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	wal := WAL{}
+	wal.file = file
+
+	return &wal, nil
+}
+
+// Close closes the write-ahead log file
+func (l *WAL) Close() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.file.Close()
+}
+
+// append durably writes a record: it returns only after the record is synced
+// to disk
+func (l *WAL) append(record walRecord) error {
+	return l.write(record, true)
+}
+
+// write writes a record syncing it to disk when asked, records written
+// without sync are durable only after the next sync
+func (l *WAL) write(record walRecord, sync bool) error {
+	line, err := json.Marshal(&record)
+
+	if err != nil {
+		return err
+	}
+
+	line = append(line, '\n')
+
+	if len(line) > maxWALRecordSize {
+		return fmt.Errorf("%w: %d bytes", errWALRecordTooLarge, len(line))
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if _, err := l.file.Write(line); err != nil {
+		return err
+	}
+
+	if !sync {
+		return nil
+	}
+
+	return l.file.Sync()
+}
+
+// sync makes the records written so far durable
+func (l *WAL) sync() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.file.Sync()
+}
+
+// truncate empties the log, to be called once its records are folded in a
+// snapshot
+func (l *WAL) truncate() error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+
+	return l.file.Sync()
+}
+
+// readWAL decodes the records from the reader passed in calling fn for each
+// of them. A malformed last line is the sign of a write torn by a crash: as
+// it was never acknowledged it is skipped.
+func readWAL(r io.Reader, fn func(record walRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxWALRecordSize)
+
+	var pending error
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		if pending != nil {
+			return pending
+		}
+
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			pending = fmt.Errorf("malformed WAL record at line %d: %w", lineNumber, err)
+			continue
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
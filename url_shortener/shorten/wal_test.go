@@ -0,0 +1,172 @@
+package shorten
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWALAppendAndReplay(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "persistence.wal")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer wal.Close()
+
+	sut := NewURLShortener(WithWAL(wal))
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	f, err := os.Open(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer f.Close()
+
+	restored := NewURLShortener()
+
+	applied, err := restored.ReplayWAL(f)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if applied != 2 {
+		t.Errorf("Incorrect applied records, got: %v, want: %v.", applied, 2)
+	}
+
+	if restored.statistics.ServerStats.TotalURL != 2 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", restored.statistics.ServerStats.TotalURL, 2)
+	}
+
+	longURL, err := restored.GetURL("f495791")
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if longURL != "https://wttr.in/Florence" {
+		t.Errorf("Incorrect long URL value, got: %s, want: %s.", longURL, "https://wttr.in/Florence")
+	}
+}
+
+func TestReplayWALRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		log         string
+		wantApplied int
+		wantErr     bool
+	}{
+		{"empty", "", 0, false},
+		{"put and delete", `{"op":"put","short_url":"a","long_url":"https://a.example"}
+{"op":"delete","short_url":"a"}
+`, 2, false},
+		{"torn last record", `{"op":"put","short_url":"a","long_url":"https://a.example"}
+{"op":"put","short_url":"b","lo`, 1, false},
+		{"malformed middle record", `{"op":"put","short_url":"a","long_url":"https://a.example"}
+garbage
+{"op":"put","short_url":"b","long_url":"https://b.example"}
+`, 1, true},
+		{"unknown operation", `{"op":"rename","short_url":"a"}
+`, 0, true},
+	}
+
+	for _, test := range tests {
+		sut := NewURLShortener()
+
+		applied, err := sut.ReplayWAL(strings.NewReader(test.log))
+
+		if !test.wantErr && err != nil {
+			t.Errorf("%s: unexpected error but got: %s.", test.name, err)
+		}
+
+		if test.wantErr && err == nil {
+			t.Errorf("%s: expected error but got nil.", test.name)
+		}
+
+		if applied != test.wantApplied {
+			t.Errorf("%s: incorrect applied records, got: %v, want: %v.", test.name, applied, test.wantApplied)
+		}
+	}
+}
+
+func TestSnapshotToTruncatesWAL(t *testing.T) {
+	dir := t.TempDir()
+	walPath := filepath.Join(dir, "persistence.wal")
+	snapshotPath := filepath.Join(dir, "persistence.json")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer wal.Close()
+
+	sut := NewURLShortener(WithWAL(wal))
+	sut.addURL("https://github.com/develersrl/powersoft-hmi", "4611ce1")
+
+	if err := sut.SnapshotTo(snapshotPath); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	walContent, _ := ioutil.ReadFile(walPath)
+	if len(walContent) != 0 {
+		t.Errorf("Expected empty WAL after snapshot but got: %s.", walContent)
+	}
+
+	sut.addURL("https://wttr.in/Florence", "f495791")
+
+	snapshot, err := os.Open(snapshotPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer snapshot.Close()
+
+	log, err := os.Open(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer log.Close()
+
+	restored := NewURLShortener()
+
+	if err := restored.UnpersistFrom(snapshot); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if _, err := restored.ReplayWAL(log); err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+
+	if restored.statistics.ServerStats.TotalURL != 2 {
+		t.Errorf("Incorrect total URL value, got: %v, want: %v.", restored.statistics.ServerStats.TotalURL, 2)
+	}
+}
+
+func TestWALRefusesLargeRecords(t *testing.T) {
+	walPath := filepath.Join(t.TempDir(), "persistence.wal")
+
+	wal, err := OpenWAL(walPath)
+	if err != nil {
+		t.Fatalf("Unexpected error but got: %s.", err)
+	}
+	defer wal.Close()
+
+	sut := NewURLShortener(WithWAL(wal))
+	longURL := "https://wttr.in/" + strings.Repeat("a", maxWALRecordSize)
+
+	if err := sut.putLink("4611ce1", Link{URL: longURL}); !errors.Is(err, errWALRecordTooLarge) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, errWALRecordTooLarge)
+	}
+
+	if _, err := sut.GetURL("4611ce1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Incorrect error, got: %v, want: %v.", err, ErrNotFound)
+	}
+
+	walContent, _ := ioutil.ReadFile(walPath)
+	if len(walContent) != 0 {
+		t.Errorf("Expected empty WAL but got %d bytes.", len(walContent))
+	}
+}